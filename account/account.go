@@ -0,0 +1,188 @@
+// Package account validates and normalizes the accounts searched for on
+// haveibeenpwned.com, so malformed input is rejected locally instead of
+// spending rate limit on a 400, and escapes them for use in a URL path.
+//
+// Email addresses are trimmed and lower cased, and their domain is converted
+// to its IDNA ASCII form, so "Jöhn@Bücher.example" becomes
+// "jöhn@xn--bcher-kva.example". Usernames are trimmed and lower cased, and
+// may hold spaces between words.
+//
+// Domains are not NFC normalized before their Punycode conversion, as IDNA
+// requires: a label typed with combining marks, such as "u" followed by
+// U+0308, is encoded differently from the same label with precomposed
+// characters. Callers accepting such input should normalize it first, e.g.
+// with golang.org/x/text/unicode/norm.
+package account
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits on the length of an email address, from RFC 5321.
+const (
+	maxLocalLen  = 64
+	maxDomainLen = 253
+	maxEmailLen  = 254
+	maxLabelLen  = 63
+
+	// maxUsernameLen is a sanity limit, HIBP does not document one.
+	maxUsernameLen = 254
+)
+
+var (
+	// ErrInvalid is wrapped by every validation error.
+	ErrInvalid = errors.New("invalid account")
+
+	// labelSeparators are the dots allowed between domain labels by IDNA.
+	labelSeparators = strings.NewReplacer("。", ".", "．", ".", "｡", ".")
+)
+
+func invalid(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: "+format, append([]interface{}{ErrInvalid}, args...)...)
+}
+
+// Normalize normalizes an email address, or a username if s holds no "@".
+func Normalize(s string) (string, error) {
+	if strings.Contains(s, "@") {
+		return Email(s)
+	}
+	return Username(s)
+}
+
+// Email validates and normalizes an email address.
+func Email(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	at := strings.LastIndexByte(s, '@')
+	if at < 0 {
+		return "", invalid("%q is not an email address", s)
+	}
+
+	local, err := localPart(s[:at])
+	if err != nil {
+		return "", err
+	}
+	domain, err := Domain(s[at+1:])
+	if err != nil {
+		return "", err
+	}
+
+	email := local + "@" + domain
+	if len(email) > maxEmailLen {
+		return "", invalid("email address is longer than %d bytes", maxEmailLen)
+	}
+	return email, nil
+}
+
+// Username validates and normalizes a username. Spaces between words are
+// kept, PathSegment escapes them as "%20".
+func Username(s string) (string, error) {
+	if !utf8.ValidString(s) {
+		return "", invalid("username is not valid UTF-8")
+	}
+
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch {
+	case s == "":
+		return "", invalid("empty username")
+	case len(s) > maxUsernameLen:
+		return "", invalid("username is longer than %d bytes", maxUsernameLen)
+	}
+
+	for _, r := range s {
+		if r != ' ' && (unicode.IsSpace(r) || unicode.IsControl(r)) {
+			return "", invalid("username %q holds whitespace other than spaces or control characters", s)
+		}
+	}
+	return s, nil
+}
+
+// Domain validates a domain name and normalizes it to lower case ASCII,
+// converting internationalized labels to Punycode.
+func Domain(s string) (string, error) {
+	if !utf8.ValidString(s) {
+		return "", invalid("domain is not valid UTF-8")
+	}
+
+	s = strings.TrimSuffix(labelSeparators.Replace(strings.ToLower(strings.TrimSpace(s))), ".")
+	if s == "" {
+		return "", invalid("empty domain")
+	}
+
+	labels := strings.Split(s, ".")
+	if len(labels) < 2 {
+		return "", invalid("domain %q has no top-level domain", s)
+	}
+	for i, label := range labels {
+		for _, r := range label {
+			if r != '-' && !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsMark(r) {
+				return "", invalid("domain %q holds %q", s, r)
+			}
+		}
+
+		ascii, err := toASCII(label)
+		if err != nil {
+			return "", invalid("domain %q: %v", s, err)
+		}
+		switch {
+		case ascii == "":
+			return "", invalid("domain %q has an empty label", s)
+		case len(ascii) > maxLabelLen:
+			return "", invalid("domain %q has a label longer than %d bytes", s, maxLabelLen)
+		case ascii[0] == '-' || ascii[len(ascii)-1] == '-':
+			return "", invalid("domain %q has a label starting or ending with a hyphen", s)
+		}
+		labels[i] = ascii
+	}
+
+	domain := strings.Join(labels, ".")
+	if len(domain) > maxDomainLen {
+		return "", invalid("domain is longer than %d bytes", maxDomainLen)
+	}
+	if strings.Trim(labels[len(labels)-1], "0123456789") == "" {
+		return "", invalid("domain %q has a numeric top-level domain", s)
+	}
+	return domain, nil
+}
+
+// localPart validates the part of an email address before the "@", which
+// may hold UTF-8 characters (RFC 6531) but no quoted strings.
+func localPart(s string) (string, error) {
+	if !utf8.ValidString(s) {
+		return "", invalid("email address is not valid UTF-8")
+	}
+
+	s = strings.ToLower(s)
+	switch {
+	case s == "":
+		return "", invalid("email address has an empty local part")
+	case len(s) > maxLocalLen:
+		return "", invalid("local part is longer than %d bytes", maxLocalLen)
+	case s[0] == '.' || s[len(s)-1] == '.' || strings.Contains(s, ".."):
+		return "", invalid("local part %q has a misplaced dot", s)
+	}
+
+	for _, r := range s {
+		if r < utf8.RuneSelf && !strings.ContainsRune(atext, r) && r != '.' {
+			return "", invalid("local part %q holds %q", s, r)
+		}
+		if r >= utf8.RuneSelf && (unicode.IsSpace(r) || unicode.IsControl(r)) {
+			return "", invalid("local part %q holds whitespace or control characters", s)
+		}
+	}
+	return s, nil
+}
+
+// atext are the ASCII characters allowed unquoted in a local part, from
+// RFC 5322.
+const atext = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!#$%&'*+-/=?^_`{|}~"
+
+// PathSegment escapes s for use as a single URL path segment, so "/" and
+// "?" cannot change the requested path and spaces are not turned into "+".
+func PathSegment(s string) string {
+	return url.PathEscape(s)
+}
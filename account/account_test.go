@@ -0,0 +1,92 @@
+package account
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmail(t *testing.T) {
+	assert := assert.New(t)
+
+	valid := map[string]string{
+		"  Account-Exists@HIBP-Integration-Tests.com ": "account-exists@hibp-integration-tests.com",
+		"first.last+tag@example.com":                   "first.last+tag@example.com",
+		"Jöhn@Bücher.example":                          "jöhn@xn--bcher-kva.example",
+		"user@例え。テスト":                                  "user@xn--r8jz45g.xn--zckzah",
+		"odd/path?@example.com":                        "odd/path?@example.com",
+		"user@example.com.":                            "user@example.com",
+	}
+	for input, want := range valid {
+		got, err := Email(input)
+		if assert.NoError(err, "[TestEmail] %q", input) {
+			assert.Equal(want, got, "[TestEmail] Unexpected normalization of %q.", input)
+		}
+	}
+
+	for _, input := range []string{
+		"",
+		"no-at-sign",
+		"@example.com",
+		"user@",
+		"user@localhost",
+		"us er@example.com",
+		".user@example.com",
+		"us..er@example.com",
+		"user(comment)@example.com",
+		"user@exa mple.com",
+		"user@-example.com",
+		"user@example..com",
+		"user@example.123",
+		"user@exa_mple.com",
+	} {
+		_, err := Email(input)
+		assert.True(errors.Is(err, ErrInvalid), "[TestEmail] Expected %q to be rejected. Got: %v", input, err)
+	}
+}
+
+func TestUsername(t *testing.T) {
+	assert := assert.New(t)
+
+	got, err := Username("  TroyHunt ")
+	if assert.NoError(err) {
+		assert.Equal("troyhunt", got)
+	}
+
+	got, err = Username(" Troy Hunt ")
+	if assert.NoError(err, "[TestUsername] Expected inner spaces to be allowed.") {
+		assert.Equal("troy hunt", got)
+	}
+
+	for _, input := range []string{"", "   ", "troy\thunt", "troy\nhunt", "troy\u00a0hunt", "troy\x00hunt", "\xff"} {
+		_, err := Username(input)
+		assert.True(errors.Is(err, ErrInvalid), "[TestUsername] Expected %q to be rejected. Got: %v", input, err)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	assert := assert.New(t)
+
+	got, err := Normalize("Foo@Example.COM")
+	assert.NoError(err)
+	assert.Equal("foo@example.com", got, "[TestNormalize] Expected input with an @ to be an email address.")
+
+	got, err = Normalize("FooBar")
+	assert.NoError(err)
+	assert.Equal("foobar", got, "[TestNormalize] Expected input without an @ to be a username.")
+}
+
+func TestPathSegment(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("odd%2Fpath%3F@example.com", PathSegment("odd/path?@example.com"))
+	assert.Equal("a%20b", PathSegment("a b"), "[TestPathSegment] Expected spaces to be escaped as %20, not +.")
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	for _, input := range []string{"\xff@example.com", "user@\xff.com"} {
+		_, err := Email(input)
+		assert.True(t, errors.Is(err, ErrInvalid), "[TestInvalidUTF8] Expected %q to be rejected. Got: %v", input, err)
+	}
+}
@@ -0,0 +1,124 @@
+package account
+
+import (
+	"errors"
+	"strings"
+)
+
+// Parameters of Punycode, as defined by RFC 3492.
+const (
+	base        = 36
+	tmin        = 1
+	tmax        = 26
+	skew        = 38
+	damp        = 700
+	initialBias = 72
+	initialN    = 128
+)
+
+// errOverflow is returned for labels too long to encode.
+var errOverflow = errors.New("punycode overflow")
+
+// toASCII converts a domain label to its ACE form, "xn--" followed by the
+// Punycode encoding, if it holds any non-ASCII character. The label is
+// encoded as given, without the NFC normalization of IDNA.
+func toASCII(label string) (string, error) {
+	for i := 0; i < len(label); i++ {
+		if label[i] >= 0x80 {
+			encoded, err := punycode(label)
+			if err != nil {
+				return "", err
+			}
+			return "xn--" + encoded, nil
+		}
+	}
+	return label, nil
+}
+
+// punycode encodes s as described in RFC 3492, section 6.3.
+func punycode(s string) (string, error) {
+	runes := []rune(s)
+
+	var out strings.Builder
+	for _, r := range runes {
+		if r < 0x80 {
+			out.WriteRune(r)
+		}
+	}
+	basic := out.Len()
+	handled := basic
+	if basic > 0 {
+		out.WriteByte('-')
+	}
+
+	n, delta, bias := rune(initialN), 0, initialBias
+	for handled < len(runes) {
+		m := rune(0x7fffffff)
+		for _, r := range runes {
+			if r >= n && r < m {
+				m = r
+			}
+		}
+
+		if int(m-n) > (1<<31-1-delta)/(handled+1) {
+			return "", errOverflow
+		}
+		delta += int(m-n) * (handled + 1)
+		n = m
+
+		for _, r := range runes {
+			if r < n {
+				delta++
+			}
+			if r != n {
+				continue
+			}
+
+			q := delta
+			for k := base; ; k += base {
+				t := k - bias
+				if t < tmin {
+					t = tmin
+				} else if t > tmax {
+					t = tmax
+				}
+				if q < t {
+					break
+				}
+				out.WriteByte(digit(t + (q-t)%(base-t)))
+				q = (q - t) / (base - t)
+			}
+			out.WriteByte(digit(q))
+
+			bias = adapt(delta, handled+1, handled == basic)
+			delta = 0
+			handled++
+		}
+		delta++
+		n++
+	}
+	return out.String(), nil
+}
+
+func adapt(delta, points int, first bool) int {
+	if first {
+		delta /= damp
+	} else {
+		delta /= 2
+	}
+	delta += delta / points
+
+	k := 0
+	for delta > ((base-tmin)*tmax)/2 {
+		delta /= base - tmin
+		k += base
+	}
+	return k + (base-tmin+1)*delta/(delta+skew)
+}
+
+func digit(d int) byte {
+	if d < 26 {
+		return byte('a' + d)
+	}
+	return byte('0' + d - 26)
+}
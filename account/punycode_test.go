@@ -0,0 +1,31 @@
+package account
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPunycode(t *testing.T) {
+	assert := assert.New(t)
+
+	// Examples from RFC 3492, section 7.1, and well known domains.
+	tests := map[string]string{
+		"bücher":            "bcher-kva",
+		"münchen":           "mnchen-3ya",
+		"例え":                "r8jz45g",
+		"テスト":               "zckzah",
+		"他们为什么不说中文":         "ihqwcrb4cv8a8dqg056pqjye",
+		"ليهمابتكلموشعربي؟": "egbpdaj6bu4bxfgehfvwxn",
+	}
+	for input, want := range tests {
+		got, err := punycode(input)
+		if assert.NoError(err) {
+			assert.Equal(want, got, "[TestPunycode] Unexpected encoding of %q.", input)
+		}
+	}
+
+	got, err := toASCII("example")
+	assert.NoError(err)
+	assert.Equal("example", got, "[TestPunycode] Expected ASCII labels to be left alone.")
+}
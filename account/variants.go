@@ -0,0 +1,75 @@
+package account
+
+import "strings"
+
+// Expander produces the variants of an email address that breach data may
+// hold for the same mailbox. Each rule is opt-in.
+type Expander struct {
+	// PlusAddressing drops a "+tag" suffix from the local part.
+	PlusAddressing bool
+
+	// GmailDots drops the dots from the local part of Gmail addresses,
+	// which Gmail ignores.
+	GmailDots bool
+
+	// GmailDomains treats gmail.com and googlemail.com as the same domain.
+	GmailDomains bool
+}
+
+// DefaultExpander applies every rule.
+var DefaultExpander = Expander{PlusAddressing: true, GmailDots: true, GmailDomains: true}
+
+// gmailDomains are the domains of Gmail mailboxes.
+var gmailDomains = []string{"gmail.com", "googlemail.com"}
+
+// Variants returns the normalized email address followed by its distinct
+// variants. Gmail ignores every placement of dots, so only the address
+// without any dot is added rather than every combination.
+func (e Expander) Variants(email string) ([]string, error) {
+	email, err := Email(email)
+	if err != nil {
+		return nil, err
+	}
+	at := strings.LastIndexByte(email, '@')
+	local, domain := email[:at], email[at+1:]
+
+	gmail := false
+	for _, d := range gmailDomains {
+		gmail = gmail || domain == d
+	}
+
+	locals := []string{local}
+	if i := strings.IndexByte(local, '+'); e.PlusAddressing && i > 0 {
+		locals = append(locals, local[:i])
+	}
+	if e.GmailDots && gmail {
+		for _, l := range locals {
+			locals = append(locals, strings.ReplaceAll(l, ".", ""))
+		}
+	}
+
+	domains := []string{domain}
+	if e.GmailDomains && gmail {
+		domains = gmailDomains
+		if domain != gmailDomains[0] {
+			domains = []string{domain, gmailDomains[0]}
+		}
+	}
+
+	var variants []string
+	seen := make(map[string]bool)
+	for _, l := range locals {
+		for _, d := range domains {
+			if v := l + "@" + d; !seen[v] {
+				seen[v] = true
+				variants = append(variants, v)
+			}
+		}
+	}
+	return variants, nil
+}
+
+// Variants returns the variants of email produced by DefaultExpander.
+func Variants(email string) ([]string, error) {
+	return DefaultExpander.Variants(email)
+}
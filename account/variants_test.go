@@ -0,0 +1,58 @@
+package account
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVariants(t *testing.T) {
+	assert := assert.New(t)
+
+	got, err := Variants("John.Doe+News@GoogleMail.com")
+	if assert.NoError(err) {
+		assert.Equal([]string{
+			"john.doe+news@googlemail.com",
+			"john.doe+news@gmail.com",
+			"john.doe@googlemail.com",
+			"john.doe@gmail.com",
+			"johndoe+news@googlemail.com",
+			"johndoe+news@gmail.com",
+			"johndoe@googlemail.com",
+			"johndoe@gmail.com",
+		}, got, "[TestVariants] Expected every rule to apply to Gmail addresses.")
+	}
+
+	got, err = Variants("first.last+tag@example.com")
+	if assert.NoError(err) {
+		assert.Equal([]string{"first.last+tag@example.com", "first.last@example.com"}, got, "[TestVariants] Expected dots to matter outside of Gmail.")
+	}
+
+	got, err = Variants("plain@example.com")
+	if assert.NoError(err) {
+		assert.Equal([]string{"plain@example.com"}, got)
+	}
+
+	_, err = Variants("not an email")
+	assert.True(errors.Is(err, ErrInvalid))
+}
+
+func TestExpanderRules(t *testing.T) {
+	assert := assert.New(t)
+
+	got, err := Expander{PlusAddressing: true}.Variants("j.doe+x@gmail.com")
+	if assert.NoError(err) {
+		assert.Equal([]string{"j.doe+x@gmail.com", "j.doe@gmail.com"}, got)
+	}
+
+	got, err = Expander{GmailDomains: true}.Variants("j.doe@gmail.com")
+	if assert.NoError(err) {
+		assert.Equal([]string{"j.doe@gmail.com", "j.doe@googlemail.com"}, got)
+	}
+
+	got, err = Expander{}.Variants("+tag@gmail.com")
+	if assert.NoError(err) {
+		assert.Equal([]string{"+tag@gmail.com"}, got, "[TestExpanderRules] Expected no variants without rules.")
+	}
+}
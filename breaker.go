@@ -0,0 +1,217 @@
+package gopwned
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type (
+	// Breaker stops calls to an endpoint group that keeps failing, so they
+	// fail fast with ErrCircuitOpen instead of waiting for a timeout. The
+	// groups are "api", every endpoint of haveibeenpwned.com, and "range",
+	// the Pwned Passwords API; each trips on its own.
+	//
+	// A group trips open once at least MinRequests of its last Window calls
+	// were made and the share that failed, with no response or a 5xx
+	// status, reaches Threshold. After Cooldown it is half-open: a single
+	// probe call is let through, closing the circuit if it succeeds and
+	// opening it again if not. Zero settings default to a Threshold of 0.5,
+	// MinRequests of 10 and Window of 20. A Breaker is safe for concurrent
+	// use.
+	Breaker struct {
+		Threshold   float64
+		MinRequests int
+		Window      int
+		Cooldown    time.Duration
+		Clock       Clock
+
+		mu       sync.Mutex
+		circuits map[string]*circuit
+	}
+
+	// State is the state of the circuit of an endpoint group.
+	State int
+
+	// circuit holds the state of one endpoint group.
+	circuit struct {
+		state    State
+		outcomes []bool
+		next     int
+		failures int
+		openedAt time.Time
+		probing  bool
+	}
+)
+
+// States of a circuit.
+const (
+	// StateClosed lets every call through.
+	StateClosed State = iota
+	// StateOpen fails every call with ErrCircuitOpen.
+	StateOpen
+	// StateHalfOpen lets a single probe call through.
+	StateHalfOpen
+)
+
+// ErrCircuitOpen is wrapped by the errors of calls rejected by a Breaker.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// Defaults of the settings of a Breaker.
+const (
+	defaultBreakerThreshold   = 0.5
+	defaultBreakerMinRequests = 10
+	defaultBreakerWindow      = 20
+)
+
+// NewBreaker creates a breaker tripping when threshold, e.g. 0.5, of the
+// last 20 calls to a group failed, probing again after cooldown.
+func NewBreaker(threshold float64, cooldown time.Duration) *Breaker {
+	return &Breaker{Threshold: threshold, MinRequests: defaultBreakerMinRequests, Window: defaultBreakerWindow, Cooldown: cooldown}
+}
+
+func (s State) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// settings returns the threshold, minimum number of calls and window of
+// the breaker, defaulting the ones that are not set.
+func (b *Breaker) settings() (threshold float64, minRequests, window int) {
+	threshold, minRequests, window = b.Threshold, b.MinRequests, b.Window
+	if threshold <= 0 {
+		threshold = defaultBreakerThreshold
+	}
+	if window <= 0 {
+		window = defaultBreakerWindow
+	}
+	if minRequests <= 0 {
+		minRequests = defaultBreakerMinRequests
+	}
+	if minRequests > window {
+		minRequests = window
+	}
+	return threshold, minRequests, window
+}
+
+func (b *Breaker) clock() Clock {
+	if b.Clock == nil {
+		return systemClock{}
+	}
+	return b.Clock
+}
+
+// State returns the state of the circuit of group, "api" or "range".
+func (b *Breaker) State(group string) State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cb := b.circuit(group)
+	if cb.state == StateOpen && !b.clock().Now().Before(cb.openedAt.Add(b.Cooldown)) {
+		return StateHalfOpen
+	}
+	return cb.state
+}
+
+func (b *Breaker) circuit(group string) *circuit {
+	if b.circuits == nil {
+		b.circuits = make(map[string]*circuit)
+	}
+	cb := b.circuits[group]
+	if cb == nil {
+		cb = &circuit{}
+		b.circuits[group] = cb
+	}
+	return cb
+}
+
+// allow reports whether the call o to group may be sent, moving an open
+// circuit past its cooldown to half-open. A nil Breaker allows every call.
+func (b *Breaker) allow(o *call, group string) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cb := b.circuit(group)
+	if cb.state == StateOpen {
+		until := cb.openedAt.Add(b.Cooldown)
+		if b.clock().Now().Before(until) {
+			o.rejected()
+			return fmt.Errorf("%w: %s endpoints until %s", ErrCircuitOpen, group, until.Format(time.RFC3339))
+		}
+		cb.state = StateHalfOpen
+		o.transition(group, StateOpen, StateHalfOpen)
+	}
+	if cb.state == StateHalfOpen {
+		if cb.probing {
+			o.rejected()
+			return fmt.Errorf("%w: %s endpoints are being probed", ErrCircuitOpen, group)
+		}
+		cb.probing = true
+	}
+	return nil
+}
+
+// record counts the outcome of a call allowed through, tripping or
+// resetting the circuit of group.
+func (b *Breaker) record(o *call, group string, resp *http.Response, err error) {
+	if b == nil {
+		return
+	}
+	failed := err != nil || resp.StatusCode >= http.StatusInternalServerError
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cb := b.circuit(group)
+	switch cb.state {
+	case StateHalfOpen:
+		cb.probing = false
+		if failed {
+			b.open(o, group, cb, StateHalfOpen)
+			return
+		}
+		cb.state = StateClosed
+		cb.outcomes, cb.next, cb.failures = nil, 0, 0
+		o.transition(group, StateHalfOpen, StateClosed)
+	case StateClosed:
+		threshold, minRequests, window := b.settings()
+		cb.add(failed, window)
+		if cb.failures > 0 && len(cb.outcomes) >= minRequests && float64(cb.failures) >= threshold*float64(len(cb.outcomes)) {
+			b.open(o, group, cb, StateClosed)
+		}
+	}
+}
+
+func (b *Breaker) open(o *call, group string, cb *circuit, from State) {
+	cb.state = StateOpen
+	cb.openedAt = b.clock().Now()
+	cb.outcomes, cb.next, cb.failures = nil, 0, 0
+	o.transition(group, from, StateOpen)
+}
+
+// add appends an outcome to the window of the last n calls.
+func (cb *circuit) add(failed bool, n int) {
+	if len(cb.outcomes) < n {
+		cb.outcomes = append(cb.outcomes, failed)
+	} else {
+		if cb.outcomes[cb.next] {
+			cb.failures--
+		}
+		cb.outcomes[cb.next] = failed
+		cb.next = (cb.next + 1) % n
+	}
+	if failed {
+		cb.failures++
+	}
+}
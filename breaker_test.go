@@ -0,0 +1,134 @@
+package gopwned
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mavjs/goPwned/hibptest"
+)
+
+func TestBreakerTrips(t *testing.T) {
+	assert := assert.New(t)
+	srv, client, logger, metrics, _ := setupObservedClient(t)
+
+	clock := newFakeClock()
+	client.Breaker = &Breaker{Threshold: 0.5, MinRequests: 4, Window: 4, Cooldown: time.Minute, Clock: clock}
+	srv.Fail("/range/", hibptest.Fault{Status: http.StatusServiceUnavailable})
+
+	for i := 0; i < 4; i++ {
+		_, err := client.GetPwnedPasswords("21BD1", false)
+		assert.False(errors.Is(err, ErrCircuitOpen), "[TestBreakerTrips] Expected calls to be sent until the circuit trips.")
+	}
+	assert.Equal(StateOpen, client.Breaker.State("range"), "[TestBreakerTrips] Expected the range circuit to trip.")
+
+	_, err := client.GetPwnedPasswords("21BD1", false)
+	assert.True(errors.Is(err, ErrCircuitOpen), "[TestBreakerTrips] Expected an open circuit to fail fast, got %v", err)
+	assert.Equal(4, srv.Hits("/range/"), "[TestBreakerTrips] Expected no request while open.")
+
+	_, err = client.GetDataClasses()
+	assert.NoError(err, "[TestBreakerTrips] Expected the api group to be unaffected.")
+	assert.Equal(StateClosed, client.Breaker.State("api"))
+
+	metrics.mu.Lock()
+	assert.Equal(1, metrics.counts["circuit_opened range 503"], "[TestBreakerTrips] Expected the trip to be counted.")
+	assert.Equal(1, metrics.counts["circuit_rejected range 0"], "[TestBreakerTrips] Expected the rejected call to be counted.")
+	metrics.mu.Unlock()
+	assert.True(strings.Contains(strings.Join(logger.records, "\n"), "WARN gopwned: circuit open [group range from closed"), "[TestBreakerTrips] Expected the trip to be logged: %v", logger.records)
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	assert := assert.New(t)
+	srv, client, _, _, _ := setupObservedClient(t)
+
+	clock := newFakeClock()
+	client.Breaker = &Breaker{Threshold: 1, MinRequests: 1, Window: 1, Cooldown: time.Minute, Clock: clock}
+	srv.Fail("/range/", hibptest.Fault{Status: http.StatusServiceUnavailable, Count: 2})
+
+	client.GetPwnedPasswords("21BD1", false)
+	assert.Equal(StateOpen, client.Breaker.State("range"))
+
+	clock.Advance(time.Minute)
+	assert.Equal(StateHalfOpen, client.Breaker.State("range"), "[TestBreakerHalfOpen] Expected the circuit to half-open after the cooldown.")
+	_, err := client.GetPwnedPasswords("21BD1", false)
+	assert.False(errors.Is(err, ErrCircuitOpen), "[TestBreakerHalfOpen] Expected a probe to be sent.")
+	assert.Equal(StateOpen, client.Breaker.State("range"), "[TestBreakerHalfOpen] Expected a failed probe to open the circuit again.")
+
+	clock.Advance(time.Minute)
+	_, err = client.GetPwnedPasswords("21BD1", false)
+	assert.NoError(err, "[TestBreakerHalfOpen] Expected the probe to succeed.")
+	assert.Equal(StateClosed, client.Breaker.State("range"), "[TestBreakerHalfOpen] Expected a successful probe to close the circuit.")
+	assert.Equal(3, srv.Hits("/range/"))
+}
+
+func TestBreakerSingleProbe(t *testing.T) {
+	assert := assert.New(t)
+	client := NewClient(nil, "")
+
+	clock := newFakeClock()
+	b := &Breaker{Threshold: 1, MinRequests: 1, Window: 1, Cooldown: time.Minute, Clock: clock}
+	b.record(client.begin("range"), "range", nil, errors.New("timeout"))
+	clock.Advance(time.Minute)
+
+	assert.NoError(b.allow(client.begin("range"), "range"), "[TestBreakerSingleProbe] Expected the first call to probe.")
+	assert.True(errors.Is(b.allow(client.begin("range"), "range"), ErrCircuitOpen), "[TestBreakerSingleProbe] Expected concurrent calls to fail while probing.")
+
+	b.record(client.begin("range"), "range", &http.Response{StatusCode: http.StatusOK}, nil)
+	assert.NoError(b.allow(client.begin("range"), "range"), "[TestBreakerSingleProbe] Expected calls once closed.")
+}
+
+func TestBreakerErrorRate(t *testing.T) {
+	assert := assert.New(t)
+	client := NewClient(nil, "")
+	b := NewBreaker(0.5, time.Minute)
+
+	ok := &http.Response{StatusCode: http.StatusOK}
+	notFound := &http.Response{StatusCode: http.StatusNotFound}
+	unavailable := &http.Response{StatusCode: http.StatusServiceUnavailable}
+	for i := 0; i < 20; i++ {
+		resp := ok
+		switch i % 4 {
+		case 1:
+			resp = notFound
+		case 3:
+			resp = unavailable
+		}
+		b.record(client.begin("api"), "api", resp, nil)
+	}
+	assert.Equal(StateClosed, b.State("api"), "[TestBreakerErrorRate] Expected 25%% of 5xx to stay under the threshold.")
+
+	for i := 0; i < 5; i++ {
+		b.record(client.begin("api"), "api", unavailable, nil)
+	}
+	assert.Equal(StateClosed, b.State("api"), "[TestBreakerErrorRate] Expected 9 failures of the last 20 calls to stay under the threshold.")
+	b.record(client.begin("api"), "api", unavailable, nil)
+	assert.Equal(StateOpen, b.State("api"), "[TestBreakerErrorRate] Expected the last 20 calls to reach the threshold.")
+}
+
+func TestBreakerZeroValue(t *testing.T) {
+	assert := assert.New(t)
+	srv, client, _, _, _ := setupObservedClient(t)
+	client.Breaker = &Breaker{Cooldown: time.Minute}
+
+	for i := 0; i < 3; i++ {
+		_, err := client.GetDataClasses()
+		assert.NoError(err, "[TestBreakerZeroValue] Expected successful calls not to trip the circuit.")
+	}
+	assert.Equal(StateClosed, client.Breaker.State("api"))
+
+	srv.Fail("/api/v3/dataclasses", hibptest.Fault{Status: http.StatusServiceUnavailable})
+	for i := 0; i < defaultBreakerMinRequests; i++ {
+		client.GetDataClasses()
+	}
+	assert.Equal(StateOpen, client.Breaker.State("api"), "[TestBreakerZeroValue] Expected the default threshold to trip the circuit.")
+
+	b := NewBreaker(0, time.Minute)
+	for i := 0; i < 2*defaultBreakerWindow; i++ {
+		b.record(client.begin("api"), "api", &http.Response{StatusCode: http.StatusOK}, nil)
+	}
+	assert.Equal(StateClosed, b.State("api"), "[TestBreakerZeroValue] Expected a zero threshold not to trip on successes.")
+}
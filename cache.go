@@ -0,0 +1,261 @@
+package gopwned
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// Cache is implemented by anything that can hold API responses between
+	// calls. Implementations must be safe for concurrent use.
+	Cache interface {
+		Get(key string) (*CacheEntry, bool)
+		Set(key string, entry *CacheEntry)
+		Delete(key string)
+	}
+
+	// CacheEntry holds a cached response body along with the validators the
+	// server sent with it, so a stale entry can be revalidated instead of
+	// downloaded again.
+	CacheEntry struct {
+		Body         []byte    `json:"body"`
+		ETag         string    `json:"etag,omitempty"`
+		LastModified string    `json:"last_modified,omitempty"`
+		Expires      time.Time `json:"expires"`
+	}
+
+	// MemoryCache is an in-memory Cache that evicts the least recently used
+	// entry once it holds more than its configured number of entries.
+	MemoryCache struct {
+		mu      sync.Mutex
+		size    int
+		ll      *list.List
+		entries map[string]*list.Element
+	}
+
+	// DiskCache is a Cache that keeps each entry as a JSON file inside a
+	// directory, so cached responses survive restarts.
+	DiskCache struct {
+		dir string
+	}
+
+	memoryItem struct {
+		key   string
+		entry *CacheEntry
+	}
+)
+
+var (
+	// defaultCacheTTL - how long a response is considered fresh, per endpoint.
+	// The breach catalogue and data classes only change a few times a week,
+	// per-account results are only cached when `Client.CacheAccounts` is set.
+	defaultCacheTTL = map[string]time.Duration{
+		"breaches":        6 * time.Hour,
+		"breach":          6 * time.Hour,
+		"dataclasses":     24 * time.Hour,
+		"breachedaccount": 15 * time.Minute,
+		"pasteaccount":    15 * time.Minute,
+	}
+)
+
+// NewMemoryCache creates an in-memory LRU cache holding at most size entries.
+// A size of 0 or less means the cache is unbounded.
+func NewMemoryCache(size int) *MemoryCache {
+	return &MemoryCache{size: size, ll: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// Get returns a copy of the entry stored under key and marks it as recently
+// used. The copy shares its Body, which must not be modified.
+func (m *MemoryCache) Get(key string) (*CacheEntry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elem, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	m.ll.MoveToFront(elem)
+	entry := *elem.Value.(*memoryItem).entry
+	return &entry, true
+}
+
+// Set stores a copy of entry under key, evicting the least recently used
+// entry if the cache is full.
+func (m *MemoryCache) Set(key string, entry *CacheEntry) {
+	copied := *entry
+	entry = &copied
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if elem, ok := m.entries[key]; ok {
+		elem.Value.(*memoryItem).entry = entry
+		m.ll.MoveToFront(elem)
+		return
+	}
+
+	m.entries[key] = m.ll.PushFront(&memoryItem{key: key, entry: entry})
+	if m.size > 0 && m.ll.Len() > m.size {
+		oldest := m.ll.Back()
+		m.ll.Remove(oldest)
+		delete(m.entries, oldest.Value.(*memoryItem).key)
+	}
+}
+
+// Delete removes the entry stored under key, if any.
+func (m *MemoryCache) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if elem, ok := m.entries[key]; ok {
+		m.ll.Remove(elem)
+		delete(m.entries, key)
+	}
+}
+
+// Len returns the number of entries currently held by the cache.
+func (m *MemoryCache) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.ll.Len()
+}
+
+// NewDiskCache creates a cache storing its entries in dir. The directory is
+// created if it does not exist yet.
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &DiskCache{dir: dir}, nil
+}
+
+func (d *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get returns the entry stored under key. Unreadable entries are treated as
+// missing.
+func (d *DiskCache) Get(key string) (*CacheEntry, bool) {
+	data, err := ioutil.ReadFile(d.path(key))
+	if err != nil {
+		return nil, false
+	}
+
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// Set stores entry under key. The entry is written to a temporary file first
+// and renamed into place, so readers never observe a partial write.
+func (d *DiskCache) Set(key string, entry *CacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	tmp, err := ioutil.TempFile(d.dir, ".tmp-")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), d.path(key)); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// Delete removes the entry stored under key, if any.
+func (d *DiskCache) Delete(key string) {
+	os.Remove(d.path(key))
+}
+
+// endpointGroup returns the first path segment of a resource, e.g. "breach"
+// for "breach/Adobe". It is used to look up per-endpoint settings.
+func endpointGroup(resource string) string {
+	resource = strings.TrimPrefix(resource, "/")
+	if i := strings.IndexAny(resource, "/?"); i >= 0 {
+		return resource[:i]
+	}
+	return resource
+}
+
+// cacheTTL returns how long responses of an endpoint group stay fresh. A TTL
+// of 0 means the endpoint is not cached.
+func (c *Client) cacheTTL(group string) time.Duration {
+	if ttl, ok := c.CacheTTL[group]; ok {
+		return ttl
+	}
+	return defaultCacheTTL[group]
+}
+
+// cacheable reports whether responses for target should go through the cache.
+// Authenticated per-account results are only cached if the caller opted in.
+func (c *Client) cacheable(group, target string) bool {
+	if c.Cache == nil || c.cacheTTL(group) <= 0 {
+		return false
+	}
+	return !checkAPI(target) || c.CacheAccounts
+}
+
+// storeResponse reads the body of resp into the cache and replaces it with an
+// in-memory copy, so the caller can decode it as usual.
+func (c *Client) storeResponse(key, group string, resp *http.Response) error {
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	c.Cache.Set(key, &CacheEntry{
+		Body:         body,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Expires:      time.Now().Add(c.cacheTTL(group)),
+	})
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return nil
+}
+
+// cachedResponse builds a response serving entry as its body.
+func cachedResponse(req *http.Request, entry *CacheEntry) *http.Response {
+	header := http.Header{}
+	if entry.ETag != "" {
+		header.Set("ETag", entry.ETag)
+	}
+	if entry.LastModified != "" {
+		header.Set("Last-Modified", entry.LastModified)
+	}
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,183 @@
+package gopwned
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupCacheServer(t *testing.T, handler http.HandlerFunc) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	gopwned := NewClient(nil, "APIKEY")
+	gopwned.BaseURL, _ = url.Parse(server.URL + "/")
+	gopwned.Cache = NewMemoryCache(10)
+	return gopwned
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := NewMemoryCache(2)
+	cache.Set("a", &CacheEntry{Body: []byte("a")})
+	cache.Set("b", &CacheEntry{Body: []byte("b")})
+
+	// Touch "a" so that "b" becomes the least recently used entry.
+	_, ok := cache.Get("a")
+	assert.True(ok, "[TestMemoryCacheEviction] Expected entry a to be cached.")
+
+	cache.Set("c", &CacheEntry{Body: []byte("c")})
+
+	_, ok = cache.Get("b")
+	assert.False(ok, "[TestMemoryCacheEviction] Expected entry b to be evicted.")
+	assert.Equal(2, cache.Len(), "[TestMemoryCacheEviction] Expected cache to hold 2 entries.")
+
+	cache.Delete("a")
+	_, ok = cache.Get("a")
+	assert.False(ok, "[TestMemoryCacheEviction] Expected entry a to be deleted.")
+}
+
+func TestDiskCache(t *testing.T) {
+	assert := assert.New(t)
+
+	cache, err := NewDiskCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("[TestDiskCache] returned error: %v", err)
+	}
+
+	want := &CacheEntry{
+		Body:    []byte(`["Account balances"]`),
+		ETag:    `"abc"`,
+		Expires: time.Now().Add(time.Hour).Round(0).UTC(),
+	}
+	cache.Set("dataclasses", want)
+
+	got, ok := cache.Get("dataclasses")
+	assert.True(ok, "[TestDiskCache] Expected entry to be cached.")
+	assert.Equal(want.Body, got.Body, "[TestDiskCache] Expected equal cached body.")
+	assert.Equal(want.ETag, got.ETag, "[TestDiskCache] Expected equal cached ETag.")
+	assert.True(want.Expires.Equal(got.Expires), "[TestDiskCache] Expected equal expiry.")
+
+	cache.Delete("dataclasses")
+	_, ok = cache.Get("dataclasses")
+	assert.False(ok, "[TestDiskCache] Expected entry to be deleted.")
+}
+
+func TestCachedDataClasses(t *testing.T) {
+	assert := assert.New(t)
+
+	hits := 0
+	gopwned := setupCacheServer(t, func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		fmt.Fprint(w, `["Account balances","Age groups"]`)
+	})
+
+	for i := 0; i < 3; i++ {
+		got, err := gopwned.GetDataClasses()
+		if err != nil {
+			t.Fatalf("[TestCachedDataClasses] returned error: %v", err)
+		}
+		assert.Equal(&DataClasses{"Account balances", "Age groups"}, got, "[TestCachedDataClasses] Expected equal value for DataClasses.")
+	}
+
+	assert.Equal(1, hits, "[TestCachedDataClasses] Expected a single request to the API.")
+}
+
+func TestCacheRevalidation(t *testing.T) {
+	assert := assert.New(t)
+
+	hits, notModified := 0, 0
+	gopwned := setupCacheServer(t, func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fmt.Fprint(w, `[{"Name":"Adobe"}]`)
+	})
+	gopwned.CacheTTL = map[string]time.Duration{"breaches": time.Nanosecond}
+
+	for i := 0; i < 2; i++ {
+		got, err := gopwned.GetBreachedSites("")
+		if err != nil {
+			t.Fatalf("[TestCacheRevalidation] returned error: %v", err)
+		}
+		assert.Equal([]*Breach{{Name: "Adobe"}}, got, "[TestCacheRevalidation] Expected equal value for breached sites.")
+		time.Sleep(time.Millisecond)
+	}
+
+	assert.Equal(2, hits, "[TestCacheRevalidation] Expected every call to reach the API.")
+	assert.Equal(1, notModified, "[TestCacheRevalidation] Expected the second call to be revalidated.")
+}
+
+func TestCacheConcurrentRevalidation(t *testing.T) {
+	gopwned := setupCacheServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fmt.Fprint(w, `["Passwords"]`)
+	})
+	gopwned.CacheTTL = map[string]time.Duration{"dataclasses": time.Nanosecond}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				got, err := gopwned.GetDataClasses()
+				if assert.NoError(t, err, "[TestCacheConcurrentRevalidation] returned error") {
+					assert.Equal(t, &DataClasses{"Passwords"}, got)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestMemoryCacheCopies(t *testing.T) {
+	cache := NewMemoryCache(1)
+	cache.Set("a", &CacheEntry{ETag: `"v1"`})
+
+	entry, _ := cache.Get("a")
+	entry.ETag = `"v2"`
+
+	stored, _ := cache.Get("a")
+	assert.Equal(t, `"v1"`, stored.ETag, "[TestMemoryCacheCopies] Expected changes to a returned entry not to reach the cache.")
+}
+
+func TestCacheSkipsAccounts(t *testing.T) {
+	assert := assert.New(t)
+
+	hits := 0
+	gopwned := setupCacheServer(t, func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		fmt.Fprint(w, `[{"Name":"Adobe"}]`)
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := gopwned.GetAccountBreaches("foo@bar.com", "", true, false); err != nil {
+			t.Fatalf("[TestCacheSkipsAccounts] returned error: %v", err)
+		}
+	}
+	assert.Equal(2, hits, "[TestCacheSkipsAccounts] Expected account results not to be cached.")
+
+	gopwned.CacheAccounts = true
+	for i := 0; i < 2; i++ {
+		if _, err := gopwned.GetAccountBreaches("foo@bar.com", "", true, false); err != nil {
+			t.Fatalf("[TestCacheSkipsAccounts] returned error: %v", err)
+		}
+	}
+	assert.Equal(3, hits, "[TestCacheSkipsAccounts] Expected account results to be cached after opting in.")
+}
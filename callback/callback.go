@@ -0,0 +1,201 @@
+// Package callback receives the notifications haveibeenpwned.com sends to a
+// domain owner when aliases of its domain show up in a new breach. Each
+// notification is checked against a shared secret, enriched with the breach
+// details and the affected aliases, and dispatched to notification sinks.
+package callback
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	gopwned "github.com/mavjs/goPwned"
+	"github.com/mavjs/goPwned/notify"
+)
+
+type (
+	// API is the subset of `*gopwned.Client` used to enrich notifications.
+	API interface {
+		GetABreachedSite(site string) (*gopwned.Breach, error)
+		GetDomainBreaches(domain string) (gopwned.DomainBreaches, error)
+	}
+
+	// Notification is the body of a domain notification callback.
+	Notification struct {
+		DomainName string `json:"DomainName"`
+		BreachName string `json:"BreachName"`
+	}
+
+	// Handler is an http.Handler accepting domain notification callbacks.
+	// Callers must authenticate with "Authorization: Bearer <Secret>".
+	Handler struct {
+		Secret string
+		API    API
+		Sinks  []notify.Sink
+
+		// Logger receives enrichment and dispatch errors. It defaults to the
+		// standard logger.
+		Logger *log.Logger
+
+		// delivered holds, per finding some sinks failed to receive, the
+		// sinks that did receive it, so a retried callback only goes to the
+		// ones that failed.
+		mu        sync.Mutex
+		delivered map[string]map[int]bool
+	}
+)
+
+// maxBodySize - the largest notification body accepted.
+const maxBodySize = 64 << 10
+
+// NewHandler creates a handler validating callbacks with secret, enriching
+// them through api and dispatching them to sinks.
+func NewHandler(secret string, api API, sinks ...notify.Sink) *Handler {
+	return &Handler{Secret: secret, API: api, Sinks: sinks, Logger: log.Default()}
+}
+
+// ServeHTTP handles a single callback. It responds with 202 once every sink
+// got the findings, and with 502 if enrichment or dispatch failed, so the
+// sender can retry. A retry is only dispatched to the sinks that failed.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var n Notification
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&n); err != nil {
+		http.Error(w, "invalid notification", http.StatusBadRequest)
+		return
+	}
+	if n.DomainName == "" || n.BreachName == "" {
+		http.Error(w, "DomainName and BreachName are required", http.StatusBadRequest)
+		return
+	}
+
+	findings, err := h.enrich(&n)
+	if err == nil {
+		err = h.dispatch(findings)
+	}
+	if err != nil {
+		h.logger().Printf("callback: %s in %s: %v", n.DomainName, n.BreachName, err)
+		http.Error(w, "unable to process notification", http.StatusBadGateway)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// authorized compares the bearer token of r with the secret in constant time.
+func (h *Handler) authorized(r *http.Request) bool {
+	if h.Secret == "" {
+		return false
+	}
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	token := strings.TrimPrefix(auth, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.Secret)) == 1
+}
+
+// enrich looks up the breach and the aliases of the domain it affected. If
+// the domain search does not list any alias yet, a single finding for the
+// whole domain is returned.
+func (h *Handler) enrich(n *Notification) ([]*notify.Finding, error) {
+	breach, err := h.API.GetABreachedSite(n.BreachName)
+	if err != nil {
+		return nil, fmt.Errorf("looking up breach: %w", err)
+	}
+
+	aliases, err := h.API.GetDomainBreaches(n.DomainName)
+	if err != nil && !errors.Is(err, gopwned.ErrNotFound) {
+		return nil, fmt.Errorf("searching domain: %w", err)
+	}
+
+	now := time.Now().UTC()
+	var findings []*notify.Finding
+	for alias, names := range aliases {
+		for _, name := range names {
+			if name == breach.Name {
+				findings = append(findings, &notify.Finding{Kind: notify.DomainTarget, Target: n.DomainName, Alias: alias, Breach: breach, FoundAt: now})
+			}
+		}
+	}
+	if len(findings) == 0 {
+		findings = append(findings, &notify.Finding{Kind: notify.DomainTarget, Target: n.DomainName, Breach: breach, FoundAt: now})
+	}
+	return findings, nil
+}
+
+// dispatch sends every finding to the sinks that have not received it yet,
+// and returns the first error once all of them were tried.
+func (h *Handler) dispatch(findings []*notify.Finding) error {
+	var first error
+	for _, f := range findings {
+		key := f.Key()
+		failed := false
+		for i, sink := range h.Sinks {
+			if h.wasDelivered(key, i) {
+				continue
+			}
+			if err := sink.Notify(f); err != nil {
+				if first == nil {
+					first = fmt.Errorf("notifying %s: %w", key, err)
+				}
+				failed = true
+				continue
+			}
+			h.markDelivered(key, i)
+		}
+		if !failed {
+			h.forget(key)
+		}
+	}
+	return first
+}
+
+func (h *Handler) wasDelivered(key string, sink int) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.delivered[key][sink]
+}
+
+func (h *Handler) markDelivered(key string, sink int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.delivered == nil {
+		h.delivered = make(map[string]map[int]bool)
+	}
+	if h.delivered[key] == nil {
+		h.delivered[key] = make(map[int]bool)
+	}
+	h.delivered[key][sink] = true
+}
+
+// forget drops the deliveries of a finding every sink received.
+func (h *Handler) forget(key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.delivered, key)
+}
+
+func (h *Handler) logger() *log.Logger {
+	if h.Logger == nil {
+		return log.Default()
+	}
+	return h.Logger
+}
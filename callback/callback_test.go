@@ -0,0 +1,160 @@
+package callback
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	gopwned "github.com/mavjs/goPwned"
+	"github.com/mavjs/goPwned/notify"
+)
+
+type fakeAPI struct {
+	aliases gopwned.DomainBreaches
+	err     error
+}
+
+func (f *fakeAPI) GetABreachedSite(site string) (*gopwned.Breach, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &gopwned.Breach{Name: site, Title: site, Domain: strings.ToLower(site) + ".com"}, nil
+}
+
+func (f *fakeAPI) GetDomainBreaches(domain string) (gopwned.DomainBreaches, error) {
+	if f.aliases == nil {
+		return nil, gopwned.ErrNotFound
+	}
+	return f.aliases, nil
+}
+
+type recorder struct {
+	keys []string
+}
+
+func (r *recorder) Notify(f *notify.Finding) error {
+	r.keys = append(r.keys, f.Key())
+	return nil
+}
+
+func setupServer(t *testing.T, api API, sinks ...notify.Sink) *httptest.Server {
+	h := NewHandler("s3cret", api, sinks...)
+	h.Logger = log.New(ioutil.Discard, "", 0)
+
+	server := httptest.NewServer(h)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func send(t *testing.T, server *httptest.Server, method, token, body string) int {
+	req, err := http.NewRequest(method, server.URL, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("unable to create request: %v", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("unable to send request: %v", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestCallback(t *testing.T) {
+	assert := assert.New(t)
+
+	rec := &recorder{}
+	api := &fakeAPI{aliases: gopwned.DomainBreaches{
+		"alice": {"Adobe", "Canva"},
+		"bob":   {"Adobe"},
+		"carol": {"Canva"},
+	}}
+	server := setupServer(t, api, rec)
+
+	status := send(t, server, "POST", "s3cret", `{"DomainName":"bar.com","BreachName":"Adobe"}`)
+	assert.Equal(http.StatusAccepted, status)
+
+	sort.Strings(rec.keys)
+	assert.Equal([]string{"domain:bar.com:alice:Adobe", "domain:bar.com:bob:Adobe"}, rec.keys, "[TestCallback] Expected a finding per affected alias.")
+}
+
+func TestCallbackWithoutAliases(t *testing.T) {
+	rec := &recorder{}
+	server := setupServer(t, &fakeAPI{}, rec)
+
+	status := send(t, server, "POST", "s3cret", `{"DomainName":"bar.com","BreachName":"Adobe"}`)
+	assert.Equal(t, http.StatusAccepted, status)
+	assert.Equal(t, []string{"domain:bar.com:Adobe"}, rec.keys, "[TestCallbackWithoutAliases] Expected a single finding for the domain.")
+}
+
+func TestCallbackRejected(t *testing.T) {
+	assert := assert.New(t)
+
+	rec := &recorder{}
+	server := setupServer(t, &fakeAPI{}, rec)
+
+	assert.Equal(http.StatusMethodNotAllowed, send(t, server, "GET", "s3cret", ""))
+	assert.Equal(http.StatusUnauthorized, send(t, server, "POST", "", `{"DomainName":"bar.com","BreachName":"Adobe"}`))
+	assert.Equal(http.StatusUnauthorized, send(t, server, "POST", "wrong", `{"DomainName":"bar.com","BreachName":"Adobe"}`))
+	assert.Equal(http.StatusBadRequest, send(t, server, "POST", "s3cret", `not json`))
+	assert.Equal(http.StatusBadRequest, send(t, server, "POST", "s3cret", `{"DomainName":"bar.com"}`))
+
+	assert.Empty(rec.keys, "[TestCallbackRejected] Expected rejected callbacks not to be dispatched.")
+}
+
+func TestCallbackEnrichmentFailure(t *testing.T) {
+	rec := &recorder{}
+	server := setupServer(t, &fakeAPI{err: errors.New("service unavailable")}, rec)
+
+	status := send(t, server, "POST", "s3cret", `{"DomainName":"bar.com","BreachName":"Adobe"}`)
+	assert.Equal(t, http.StatusBadGateway, status, "[TestCallbackEnrichmentFailure] Expected a retryable status.")
+	assert.Empty(t, rec.keys)
+}
+
+func TestCallbackSinkFailure(t *testing.T) {
+	failing := notify.SinkFunc(func(f *notify.Finding) error { return errors.New("sink unavailable") })
+	server := setupServer(t, &fakeAPI{}, failing)
+
+	status := send(t, server, "POST", "s3cret", `{"DomainName":"bar.com","BreachName":"Adobe"}`)
+	assert.Equal(t, http.StatusBadGateway, status)
+}
+
+func TestCallbackRetriesOnlyFailedSinks(t *testing.T) {
+	assert := assert.New(t)
+
+	ok := &recorder{}
+	down := true
+	var retried []string
+	flaky := notify.SinkFunc(func(f *notify.Finding) error {
+		if down {
+			return errors.New("sink unavailable")
+		}
+		retried = append(retried, f.Key())
+		return nil
+	})
+	server := setupServer(t, &fakeAPI{aliases: gopwned.DomainBreaches{"alice": {"Adobe"}, "bob": {"Adobe"}}}, ok, flaky)
+
+	status := send(t, server, "POST", "s3cret", `{"DomainName":"bar.com","BreachName":"Adobe"}`)
+	assert.Equal(http.StatusBadGateway, status, "[TestCallbackRetriesOnlyFailedSinks] Expected a retryable status.")
+	assert.Len(ok.keys, 2)
+
+	down = false
+	status = send(t, server, "POST", "s3cret", `{"DomainName":"bar.com","BreachName":"Adobe"}`)
+	assert.Equal(http.StatusAccepted, status)
+	assert.Len(ok.keys, 2, "[TestCallbackRetriesOnlyFailedSinks] Expected no alert to be repeated to the sink that succeeded.")
+	assert.Len(retried, 2, "[TestCallbackRetriesOnlyFailedSinks] Expected the failed sink to get every finding on the retry.")
+
+	// Once delivered everywhere, a new notification goes to every sink.
+	send(t, server, "POST", "s3cret", `{"DomainName":"bar.com","BreachName":"Adobe"}`)
+	assert.Len(ok.keys, 4)
+}
@@ -0,0 +1,229 @@
+// Package catalog keeps a local copy of the haveibeenpwned.com breach
+// catalogue, so it can be queried offline by domain, data class, date range,
+// flags or a fuzzy title match.
+package catalog
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	gopwned "github.com/mavjs/goPwned"
+)
+
+type (
+	// Source is implemented by anything that can return the full list of
+	// breaches, such as `*gopwned.Client`.
+	Source interface {
+		GetBreachedSites(domainFilter string) ([]*gopwned.Breach, error)
+	}
+
+	// Index holds the breach catalogue in memory, keyed by breach name. It is
+	// safe for concurrent use.
+	Index struct {
+		mu       sync.RWMutex
+		path     string
+		lastSync time.Time
+		breaches map[string]*gopwned.Breach
+	}
+
+	// SyncResult lists the names of the breaches that changed during a Sync,
+	// along with the detailed changes between the old and new index.
+	SyncResult struct {
+		Added   []string
+		Updated []string
+		Removed []string
+		Changes []Change
+	}
+
+	// persisted is the on-disk layout of an Index.
+	persisted struct {
+		LastSync time.Time         `json:"last_sync"`
+		Breaches []*gopwned.Breach `json:"breaches"`
+	}
+)
+
+// dateLayouts - layouts used by the API for `BreachDate`, `AddedDate` and
+// `ModifiedDate`. Older breaches omit the seconds.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+}
+
+// ParseDate parses any of the date formats used in breach records.
+func ParseDate(value string) (time.Time, error) {
+	var err error
+	for _, layout := range dateLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// New creates an empty index persisted to path. An empty path keeps the
+// index in memory only.
+func New(path string) *Index {
+	return &Index{path: path, breaches: make(map[string]*gopwned.Breach)}
+}
+
+// Open loads the index persisted at path. A missing file is not an error and
+// results in an empty index.
+func Open(path string) (*Index, error) {
+	idx := New(path)
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var p persisted
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+
+	idx.lastSync = p.LastSync
+	for _, b := range p.Breaches {
+		idx.breaches[b.Name] = b
+	}
+	return idx, nil
+}
+
+// Save writes the index to its persistence file. The file is replaced
+// atomically, so a crash never leaves a truncated index behind.
+func (i *Index) Save() error {
+	if i.path == "" {
+		return nil
+	}
+
+	i.mu.RLock()
+	data, err := json.Marshal(persisted{LastSync: i.lastSync, Breaches: i.all()})
+	i.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(i.path), ".catalog-")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), i.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// Sync fetches the breach list from src and merges it into the index. Only
+// breaches that are new or whose `ModifiedDate` moved forward replace the
+// stored copy, breaches no longer listed are dropped. The index is saved
+// afterwards if it has a persistence file.
+func (i *Index) Sync(src Source) (*SyncResult, error) {
+	fetched, err := src.GetBreachedSites("")
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SyncResult{}
+	seen := make(map[string]bool, len(fetched))
+
+	i.mu.Lock()
+	before := i.all()
+	for _, b := range fetched {
+		seen[b.Name] = true
+
+		old, ok := i.breaches[b.Name]
+		switch {
+		case !ok:
+			result.Added = append(result.Added, b.Name)
+		case modifiedAfter(b, old):
+			result.Updated = append(result.Updated, b.Name)
+		default:
+			continue
+		}
+		i.breaches[b.Name] = b
+	}
+	for name := range i.breaches {
+		if !seen[name] {
+			result.Removed = append(result.Removed, name)
+			delete(i.breaches, name)
+		}
+	}
+	i.lastSync = time.Now().UTC()
+	result.Changes = Diff(before, i.all())
+	i.mu.Unlock()
+
+	sort.Strings(result.Added)
+	sort.Strings(result.Updated)
+	sort.Strings(result.Removed)
+
+	return result, i.Save()
+}
+
+// modifiedAfter reports whether b was modified after old. Unparsable dates
+// are compared as strings, which still orders ISO 8601 values correctly.
+func modifiedAfter(b, old *gopwned.Breach) bool {
+	newer, err1 := ParseDate(b.ModifiedDate)
+	older, err2 := ParseDate(old.ModifiedDate)
+	if err1 != nil || err2 != nil {
+		return b.ModifiedDate > old.ModifiedDate
+	}
+	return newer.After(older)
+}
+
+// LastSync returns the time of the last successful Sync.
+func (i *Index) LastSync() time.Time {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return i.lastSync
+}
+
+// Len returns the number of breaches in the index.
+func (i *Index) Len() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return len(i.breaches)
+}
+
+// Get returns a single breach by its name, e.g. "Adobe".
+func (i *Index) Get(name string) (*gopwned.Breach, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	b, ok := i.breaches[name]
+	return b, ok
+}
+
+// All returns every breach in the index, ordered by name.
+func (i *Index) All() []*gopwned.Breach {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return i.all()
+}
+
+func (i *Index) all() []*gopwned.Breach {
+	breaches := make([]*gopwned.Breach, 0, len(i.breaches))
+	for _, b := range i.breaches {
+		breaches = append(breaches, b)
+	}
+	sort.Slice(breaches, func(a, b int) bool { return breaches[a].Name < breaches[b].Name })
+	return breaches
+}
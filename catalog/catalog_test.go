@@ -0,0 +1,156 @@
+package catalog
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	gopwned "github.com/mavjs/goPwned"
+)
+
+// fixtureSource serves a breach list loaded from testdata.
+type fixtureSource struct {
+	breaches []*gopwned.Breach
+	calls    int
+}
+
+func (f *fixtureSource) GetBreachedSites(domainFilter string) ([]*gopwned.Breach, error) {
+	f.calls++
+	return f.breaches, nil
+}
+
+func loadFixture(t *testing.T, name string) []*gopwned.Breach {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("unable to read fixture %s: %v", name, err)
+	}
+
+	var breaches []*gopwned.Breach
+	if err := json.Unmarshal(data, &breaches); err != nil {
+		t.Fatalf("unable to decode fixture %s: %v", name, err)
+	}
+	return breaches
+}
+
+func setupIndex(t *testing.T) *Index {
+	idx := New("")
+	if _, err := idx.Sync(&fixtureSource{breaches: loadFixture(t, "breaches.json")}); err != nil {
+		t.Fatalf("unable to sync index: %v", err)
+	}
+	return idx
+}
+
+func names(breaches []*gopwned.Breach) []string {
+	var n []string
+	for _, b := range breaches {
+		n = append(n, b.Name)
+	}
+	return n
+}
+
+func TestParseDate(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, value := range []string{"2013-12-04", "2013-12-04T00:00Z", "2013-12-04T00:00:00Z"} {
+		got, err := ParseDate(value)
+		if err != nil {
+			t.Fatalf("[TestParseDate] returned error for %s: %v", value, err)
+		}
+		assert.Equal(time.Date(2013, 12, 4, 0, 0, 0, 0, time.UTC), got.UTC(), "[TestParseDate] Expected equal dates.")
+	}
+
+	_, err := ParseDate("yesterday")
+	assert.Error(err, "[TestParseDate] Expected an error for an invalid date.")
+}
+
+func TestSyncIncremental(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "catalog.json")
+	src := &fixtureSource{breaches: loadFixture(t, "breaches.json")}
+
+	idx := New(path)
+	result, err := idx.Sync(src)
+	if err != nil {
+		t.Fatalf("[TestSyncIncremental] returned error: %v", err)
+	}
+	assert.Len(result.Added, 5, "[TestSyncIncremental] Expected every breach to be added.")
+
+	// Only Adobe moves its ModifiedDate forward, Dailymotion is gone.
+	updated := loadFixture(t, "breaches.json")
+	updated[0].ModifiedDate = "2023-01-01T00:00:00Z"
+	updated[0].PwnCount++
+	updated[1].PwnCount++ // not modified according to ModifiedDate
+	src.breaches = updated[:4]
+
+	result, err = idx.Sync(src)
+	if err != nil {
+		t.Fatalf("[TestSyncIncremental] returned error: %v", err)
+	}
+	assert.Equal([]string{"Adobe"}, result.Updated, "[TestSyncIncremental] Expected only modified breaches to be updated.")
+	assert.Equal([]string{"Dailymotion"}, result.Removed, "[TestSyncIncremental] Expected Dailymotion to be removed.")
+	assert.Empty(result.Added, "[TestSyncIncremental] Expected no breaches to be added.")
+	assert.Len(result.Changes, 2, "[TestSyncIncremental] Expected a pwn count change and a removal.")
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("[TestSyncIncremental] returned error on open: %v", err)
+	}
+	assert.Equal(4, reopened.Len(), "[TestSyncIncremental] Expected the persisted index to hold 4 breaches.")
+	adobe, _ := reopened.Get("Adobe")
+	assert.Equal(152445166, adobe.PwnCount, "[TestSyncIncremental] Expected the updated Adobe breach to be persisted.")
+	assert.False(reopened.LastSync().IsZero(), "[TestSyncIncremental] Expected the last sync time to be persisted.")
+}
+
+func TestOpenMissing(t *testing.T) {
+	idx, err := Open(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("[TestOpenMissing] returned error: %v", err)
+	}
+	assert.Equal(t, 0, idx.Len(), "[TestOpenMissing] Expected an empty index.")
+}
+
+func TestFind(t *testing.T) {
+	assert := assert.New(t)
+
+	idx := setupIndex(t)
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{"domain", Query{Domain: "Adobe.com"}, []string{"Adobe"}},
+		{"data classes", Query{DataClasses: []string{"Passwords", "phone numbers"}}, []string{"AshleyMadison"}},
+		{"date range", Query{From: time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2016, 12, 31, 0, 0, 0, 0, time.UTC)}, []string{"AshleyMadison", "Dailymotion"}},
+		{"sensitive", Query{With: Sensitive}, []string{"AshleyMadison"}},
+		{"verified without spam lists", Query{With: Verified, Without: SpamList | Sensitive}, []string{"Adobe", "BattlefieldHeroes"}},
+		{"unverified", Query{Without: Verified}, []string{"Dailymotion"}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(tt.want, names(idx.Find(tt.query)), "[TestFind] Unexpected result for %s query.", tt.name)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	assert := assert.New(t)
+
+	idx := setupIndex(t)
+
+	matches := idx.Search("adobee")
+	if assert.Len(matches, 1, "[TestSearch] Expected a single fuzzy match.") {
+		assert.Equal("Adobe", matches[0].Breach.Name)
+	}
+
+	matches = idx.Search("battlefield hero")
+	if assert.NotEmpty(matches, "[TestSearch] Expected a substring match.") {
+		assert.Equal("BattlefieldHeroes", matches[0].Breach.Name)
+	}
+
+	assert.Empty(idx.Search("nothing like it"), "[TestSearch] Expected no matches.")
+}
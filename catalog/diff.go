@@ -0,0 +1,189 @@
+package catalog
+
+import (
+	"sort"
+	"strings"
+
+	gopwned "github.com/mavjs/goPwned"
+)
+
+type (
+	// ChangeKind identifies what changed about a breach between two snapshots.
+	ChangeKind int
+
+	// Change is a single difference between two breach snapshots. Old is nil
+	// for added breaches and New is nil for removed ones.
+	Change struct {
+		Kind ChangeKind      `json:"kind"`
+		Name string          `json:"name"`
+		Old  *gopwned.Breach `json:"old,omitempty"`
+		New  *gopwned.Breach `json:"new,omitempty"`
+
+		// Flag and Set describe a FlagChanged event: which flag flipped and
+		// its new value.
+		Flag Flag `json:"flag,omitempty"`
+		Set  bool `json:"set"`
+
+		// DataClasses lists the data classes added or removed.
+		DataClasses []string `json:"data_classes,omitempty"`
+	}
+)
+
+// Kinds of changes reported by Diff.
+const (
+	BreachAdded ChangeKind = iota
+	BreachRemoved
+	PwnCountChanged
+	DataClassesAdded
+	DataClassesRemoved
+	FlagChanged
+	DescriptionChanged
+)
+
+var (
+	changeKindNames = map[ChangeKind]string{
+		BreachAdded:        "breach_added",
+		BreachRemoved:      "breach_removed",
+		PwnCountChanged:    "pwn_count_changed",
+		DataClassesAdded:   "data_classes_added",
+		DataClassesRemoved: "data_classes_removed",
+		FlagChanged:        "flag_changed",
+		DescriptionChanged: "description_changed",
+	}
+
+	flagNames = []struct {
+		flag Flag
+		name string
+	}{
+		{Verified, "IsVerified"},
+		{Fabricated, "IsFabricated"},
+		{Sensitive, "IsSensitive"},
+		{Retired, "IsRetired"},
+		{SpamList, "IsSpamList"},
+		{Malware, "IsMalware"},
+	}
+)
+
+func (k ChangeKind) String() string {
+	if name, ok := changeKindNames[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// MarshalText encodes the kind by its name, so events stay readable as JSON.
+func (k ChangeKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// String returns the breach field names of the flags, joined with "|".
+func (f Flag) String() string {
+	var set []string
+	for _, fn := range flagNames {
+		if f&fn.flag != 0 {
+			set = append(set, fn.name)
+		}
+	}
+	return strings.Join(set, "|")
+}
+
+// MarshalText encodes the flag by its field names.
+func (f Flag) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// Diff compares two snapshots of the breach list and returns every change,
+// ordered by breach name and then by kind.
+func Diff(old, new []*gopwned.Breach) []Change {
+	before := make(map[string]*gopwned.Breach, len(old))
+	for _, b := range old {
+		before[b.Name] = b
+	}
+
+	var changes []Change
+	seen := make(map[string]bool, len(new))
+	for _, b := range new {
+		seen[b.Name] = true
+
+		o, ok := before[b.Name]
+		if !ok {
+			changes = append(changes, Change{Kind: BreachAdded, Name: b.Name, New: b})
+			continue
+		}
+		changes = append(changes, diffBreach(o, b)...)
+	}
+	for _, b := range old {
+		if !seen[b.Name] {
+			changes = append(changes, Change{Kind: BreachRemoved, Name: b.Name, Old: b})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Name != changes[j].Name {
+			return changes[i].Name < changes[j].Name
+		}
+		return changes[i].Kind < changes[j].Kind
+	})
+	return changes
+}
+
+// diffBreach compares two versions of the same breach.
+func diffBreach(o, n *gopwned.Breach) []Change {
+	var changes []Change
+	change := func(kind ChangeKind) Change {
+		return Change{Kind: kind, Name: n.Name, Old: o, New: n}
+	}
+
+	if o.PwnCount != n.PwnCount {
+		changes = append(changes, change(PwnCountChanged))
+	}
+
+	oldClasses, newClasses := classSet(o), classSet(n)
+	if added := missing(newClasses, oldClasses); len(added) > 0 {
+		c := change(DataClassesAdded)
+		c.DataClasses = added
+		changes = append(changes, c)
+	}
+	if removed := missing(oldClasses, newClasses); len(removed) > 0 {
+		c := change(DataClassesRemoved)
+		c.DataClasses = removed
+		changes = append(changes, c)
+	}
+
+	oldFlags, newFlags := flags(o), flags(n)
+	for _, fn := range flagNames {
+		if (oldFlags^newFlags)&fn.flag != 0 {
+			c := change(FlagChanged)
+			c.Flag = fn.flag
+			c.Set = newFlags&fn.flag != 0
+			changes = append(changes, c)
+		}
+	}
+
+	if o.Description != n.Description {
+		changes = append(changes, change(DescriptionChanged))
+	}
+	return changes
+}
+
+func classSet(b *gopwned.Breach) map[string]bool {
+	set := make(map[string]bool)
+	if b.DataClasses != nil {
+		for _, class := range *b.DataClasses {
+			set[class] = true
+		}
+	}
+	return set
+}
+
+// missing returns the sorted keys of a that are not in b.
+func missing(a, b map[string]bool) []string {
+	var keys []string
+	for k := range a {
+		if !b[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
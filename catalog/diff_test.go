@@ -0,0 +1,66 @@
+package catalog
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	assert := assert.New(t)
+
+	changes := Diff(loadFixture(t, "breaches.json"), loadFixture(t, "breaches-next.json"))
+
+	type event struct {
+		kind ChangeKind
+		name string
+	}
+	var got []event
+	for _, c := range changes {
+		got = append(got, event{c.Kind, c.Name})
+	}
+
+	want := []event{
+		{PwnCountChanged, "Adobe"},
+		{FlagChanged, "BattlefieldHeroes"},
+		{DescriptionChanged, "BattlefieldHeroes"},
+		{BreachAdded, "Canva"},
+		{DataClassesAdded, "Dailymotion"},
+		{FlagChanged, "Dailymotion"},
+		{BreachRemoved, "RiverCityMedia"},
+	}
+	assert.Equal(want, got, "[TestDiff] Expected typed change events between fixture snapshots.")
+
+	assert.Equal(152445165, changes[0].Old.PwnCount, "[TestDiff] Expected the old pwn count.")
+	assert.Equal(152445200, changes[0].New.PwnCount, "[TestDiff] Expected the new pwn count.")
+
+	assert.Equal(Retired, changes[1].Flag, "[TestDiff] Expected IsRetired to flip.")
+	assert.True(changes[1].Set, "[TestDiff] Expected IsRetired to be set.")
+
+	assert.Equal([]string{"IP addresses"}, changes[4].DataClasses, "[TestDiff] Expected the added data class.")
+	assert.Equal(Verified, changes[5].Flag, "[TestDiff] Expected IsVerified to flip.")
+
+	assert.Nil(changes[6].New, "[TestDiff] Expected no new breach for a removal.")
+}
+
+func TestDiffUnchanged(t *testing.T) {
+	breaches := loadFixture(t, "breaches.json")
+	assert.Empty(t, Diff(breaches, loadFixture(t, "breaches.json")), "[TestDiffUnchanged] Expected no changes between equal snapshots.")
+}
+
+func TestChangeJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	data, err := json.Marshal(Change{Kind: FlagChanged, Name: "Dailymotion", Flag: Verified, Set: true})
+	if err != nil {
+		t.Fatalf("[TestChangeJSON] returned error: %v", err)
+	}
+	assert.JSONEq(`{"kind":"flag_changed","name":"Dailymotion","flag":"IsVerified","set":true}`, string(data))
+
+	data, err = json.Marshal(Change{Kind: FlagChanged, Name: "Dailymotion", Flag: Sensitive, Set: false})
+	if err != nil {
+		t.Fatalf("[TestChangeJSON] returned error: %v", err)
+	}
+	assert.JSONEq(`{"kind":"flag_changed","name":"Dailymotion","flag":"IsSensitive","set":false}`, string(data), "[TestChangeJSON] Expected a cleared flag to be serialized.")
+}
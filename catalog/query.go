@@ -0,0 +1,222 @@
+package catalog
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	gopwned "github.com/mavjs/goPwned"
+)
+
+type (
+	// Flag selects one of the boolean `Is*` fields of a breach.
+	Flag uint8
+
+	// Query filters breaches in the index. All non-zero fields must match.
+	Query struct {
+		// Domain matches the breached domain, case insensitive.
+		Domain string
+		// DataClasses lists data classes that must all be exposed, e.g.
+		// "Passwords" and "Phone numbers".
+		DataClasses []string
+		// From and To bound the `BreachDate`, both inclusive.
+		From, To time.Time
+		// With lists flags that must be set, Without flags that must not be.
+		With, Without Flag
+	}
+
+	// Match is a breach found by Search along with how well its title matched,
+	// from 0 (no match) to 1 (exact match).
+	Match struct {
+		Breach *gopwned.Breach
+		Score  float64
+	}
+)
+
+// Flags matching the `Is*` fields of a breach, combine them with `|`.
+const (
+	Verified Flag = 1 << iota
+	Fabricated
+	Sensitive
+	Retired
+	SpamList
+	Malware
+)
+
+// minScore - the lowest score Search still considers a match.
+const minScore = 0.7
+
+// flags returns the flags set on b.
+func flags(b *gopwned.Breach) Flag {
+	var f Flag
+	for flag, set := range map[Flag]bool{
+		Verified:   b.IsVerified,
+		Fabricated: b.IsFabricated,
+		Sensitive:  b.IsSensitive,
+		Retired:    b.IsRetired,
+		SpamList:   b.IsSpamList,
+		Malware:    b.IsMalware,
+	} {
+		if set {
+			f |= flag
+		}
+	}
+	return f
+}
+
+// Match reports whether b satisfies every condition of the query.
+func (q *Query) Match(b *gopwned.Breach) bool {
+	if q.Domain != "" && !strings.EqualFold(q.Domain, b.Domain) {
+		return false
+	}
+
+	f := flags(b)
+	if f&q.With != q.With || f&q.Without != 0 {
+		return false
+	}
+
+	if !q.From.IsZero() || !q.To.IsZero() {
+		date, err := ParseDate(b.BreachDate)
+		if err != nil {
+			return false
+		}
+		if !q.From.IsZero() && date.Before(q.From) {
+			return false
+		}
+		if !q.To.IsZero() && date.After(q.To) {
+			return false
+		}
+	}
+
+	if len(q.DataClasses) > 0 {
+		if b.DataClasses == nil {
+			return false
+		}
+		exposed := make(map[string]bool, len(*b.DataClasses))
+		for _, class := range *b.DataClasses {
+			exposed[strings.ToLower(class)] = true
+		}
+		for _, class := range q.DataClasses {
+			if !exposed[strings.ToLower(class)] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Find returns the breaches matching q, ordered by name.
+func (i *Index) Find(q Query) []*gopwned.Breach {
+	var found []*gopwned.Breach
+	for _, b := range i.All() {
+		if q.Match(b) {
+			found = append(found, b)
+		}
+	}
+	return found
+}
+
+// Search returns the breaches whose title or name approximately matches
+// term, best matches first. Matching is case insensitive, ignores punctuation
+// and tolerates small typos.
+func (i *Index) Search(term string) []Match {
+	needle := normalize(term)
+	if needle == "" {
+		return nil
+	}
+
+	var matches []Match
+	for _, b := range i.All() {
+		score := titleScore(needle, b.Title)
+		if s := titleScore(needle, b.Name); s > score {
+			score = s
+		}
+		if score >= minScore {
+			matches = append(matches, Match{Breach: b, Score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(a, b int) bool { return matches[a].Score > matches[b].Score })
+	return matches
+}
+
+// titleScore scores needle against the whole title and each of its words,
+// returning the best score.
+func titleScore(needle, title string) float64 {
+	haystack := normalize(title)
+	if haystack == "" {
+		return 0
+	}
+	if haystack == needle {
+		return 1
+	}
+	if strings.Contains(haystack, needle) {
+		return 0.9
+	}
+
+	best := similarity(needle, haystack)
+	for _, word := range strings.Fields(strings.ToLower(title)) {
+		if s := similarity(needle, normalize(word)); s > best {
+			best = s
+		}
+	}
+	return best * 0.9
+}
+
+// normalize lower cases s and strips everything but letters and digits.
+func normalize(s string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// similarity returns 1 minus the Levenshtein distance between a and b,
+// relative to the length of the longer string.
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min3(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	gopwned "github.com/mavjs/goPwned"
+	"github.com/mavjs/goPwned/catalog"
+)
+
+type (
+	// API is the part of `gopwned.Client` the exporter needs.
+	API interface {
+		GetBreachedSites(domainFilter string) ([]*gopwned.Breach, error)
+		GetAccountBreaches(account, domain string, truncate, unverified bool) ([]*gopwned.Breach, error)
+		GetDomainBreaches(domain string) (gopwned.DomainBreaches, error)
+	}
+
+	// exporter refreshes exposure figures from the API and serves them as
+	// OpenMetrics.
+	exporter struct {
+		api      API
+		accounts []string
+		domains  []string
+		logger   *log.Logger
+
+		mu       sync.Mutex
+		current  *snapshot
+		failures int
+	}
+
+	// snapshot holds the figures of a single refresh.
+	snapshot struct {
+		refreshed   time.Time
+		breaches    int
+		pwnCount    int64
+		latestAdded time.Time
+		targets     map[string]*exposure
+	}
+
+	// exposure holds the figures of a watched account or domain.
+	exposure struct {
+		kind        string
+		target      string
+		aliases     int
+		breaches    int
+		newest      time.Time
+		dataClasses map[string]int
+	}
+)
+
+func newExporter(api API, accounts, domains []string) *exporter {
+	return &exporter{api: api, accounts: accounts, domains: domains, logger: log.Default()}
+}
+
+// run refreshes every interval until ctx is done.
+func (e *exporter) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.refresh(); err != nil {
+			e.logger.Printf("refresh failed: %v", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// refresh fetches the catalogue and the exposure of every target. Targets
+// that fail keep the figures of the previous refresh.
+func (e *exporter) refresh() error {
+	all, err := e.api.GetBreachedSites("")
+	if err != nil {
+		e.fail()
+		return err
+	}
+
+	snap := &snapshot{refreshed: time.Now(), breaches: len(all), targets: make(map[string]*exposure)}
+	byName := make(map[string]*gopwned.Breach, len(all))
+	for _, b := range all {
+		byName[strings.ToLower(b.Name)] = b
+		snap.pwnCount += int64(b.PwnCount)
+		if added, err := catalog.ParseDate(b.AddedDate); err == nil && added.After(snap.latestAdded) {
+			snap.latestAdded = added
+		}
+	}
+
+	e.mu.Lock()
+	previous := e.current
+	e.mu.Unlock()
+
+	var failed []error
+	for _, account := range e.accounts {
+		exp, err := e.account(account, byName)
+		snap.keep("account", account, exp, err, previous, &failed)
+	}
+	for _, domain := range e.domains {
+		exp, err := e.domain(domain, byName)
+		snap.keep("domain", domain, exp, err, previous, &failed)
+	}
+
+	e.mu.Lock()
+	e.current = snap
+	e.failures += len(failed)
+	e.mu.Unlock()
+
+	if len(failed) > 0 {
+		return failed[0]
+	}
+	return nil
+}
+
+func (e *exporter) fail() {
+	e.mu.Lock()
+	e.failures++
+	e.mu.Unlock()
+}
+
+// keep adds the exposure of a target to s, or the previous one if fetching
+// it failed.
+func (s *snapshot) keep(kind, target string, exp *exposure, err error, previous *snapshot, failed *[]error) {
+	key := kind + ":" + target
+	if err != nil {
+		*failed = append(*failed, err)
+		if previous != nil && previous.targets[key] != nil {
+			s.targets[key] = previous.targets[key]
+		}
+		return
+	}
+	s.targets[key] = exp
+}
+
+func (e *exporter) account(account string, byName map[string]*gopwned.Breach) (*exposure, error) {
+	exp := &exposure{kind: "account", target: account, dataClasses: make(map[string]int)}
+
+	breaches, err := e.api.GetAccountBreaches(account, "", true, true)
+	if errors.Is(err, gopwned.ErrNotFound) {
+		return exp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(breaches) > 0 {
+		exp.aliases = 1
+	}
+	for _, b := range breaches {
+		exp.add(b.Name, byName)
+	}
+	return exp, nil
+}
+
+func (e *exporter) domain(domain string, byName map[string]*gopwned.Breach) (*exposure, error) {
+	exp := &exposure{kind: "domain", target: domain, dataClasses: make(map[string]int)}
+
+	aliases, err := e.api.GetDomainBreaches(domain)
+	if errors.Is(err, gopwned.ErrNotFound) {
+		return exp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	exp.aliases = len(aliases)
+	seen := make(map[string]bool)
+	for _, names := range aliases {
+		for _, name := range names {
+			if key := strings.ToLower(name); !seen[key] {
+				seen[key] = true
+				exp.add(name, byName)
+			}
+		}
+	}
+	return exp, nil
+}
+
+// add counts a breach of the target, looked up in the catalogue for its
+// date and data classes.
+func (x *exposure) add(name string, byName map[string]*gopwned.Breach) {
+	x.breaches++
+
+	b := byName[strings.ToLower(name)]
+	if b == nil {
+		return
+	}
+	if date, err := catalog.ParseDate(b.BreachDate); err == nil && date.After(x.newest) {
+		x.newest = date
+	}
+	if b.DataClasses != nil {
+		for _, class := range *b.DataClasses {
+			x.dataClasses[class]++
+		}
+	}
+}
+
+func (e *exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	snap, failures := e.current, e.failures
+	e.mu.Unlock()
+
+	var buf bytes.Buffer
+	if err := writeMetrics(&metricWriter{w: &buf}, snap, failures); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", openMetricsType)
+	w.Write(buf.Bytes())
+}
+
+// writeMetrics writes the figures of snap, which is nil before the first
+// successful refresh.
+func writeMetrics(m *metricWriter, snap *snapshot, failures int) error {
+	m.family("hibp_exporter_refresh_errors", "counter", "Failed requests while refreshing.")
+	m.sample("hibp_exporter_refresh_errors_total", float64(failures))
+
+	if snap == nil {
+		return m.end()
+	}
+
+	m.family("hibp_exporter_last_refresh_timestamp_seconds", "gauge", "Time of the last refresh.")
+	m.sample("hibp_exporter_last_refresh_timestamp_seconds", unix(snap.refreshed))
+	m.family("hibp_breaches", "gauge", "Number of breaches in the HIBP catalogue.")
+	m.sample("hibp_breaches", float64(snap.breaches))
+	m.family("hibp_breached_accounts", "gauge", "Sum of the accounts of every breach in the HIBP catalogue.")
+	m.sample("hibp_breached_accounts", float64(snap.pwnCount))
+	m.family("hibp_latest_breach_added_timestamp_seconds", "gauge", "Time the most recent breach was added to HIBP.")
+	m.sample("hibp_latest_breach_added_timestamp_seconds", unix(snap.latestAdded))
+
+	keys := make([]string, 0, len(snap.targets))
+	for key := range snap.targets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	m.family("hibp_target_breached_aliases", "gauge", "Number of breached aliases of a watched domain, or 1 if a watched account is breached.")
+	for _, key := range keys {
+		x := snap.targets[key]
+		m.sample("hibp_target_breached_aliases", float64(x.aliases), x.labels()...)
+	}
+	m.family("hibp_target_breaches", "gauge", "Number of breaches a watched account or domain appears in.")
+	for _, key := range keys {
+		x := snap.targets[key]
+		m.sample("hibp_target_breaches", float64(x.breaches), x.labels()...)
+	}
+	m.family("hibp_target_newest_breach_timestamp_seconds", "gauge", "Date of the newest breach a watched account or domain appears in.")
+	for _, key := range keys {
+		if x := snap.targets[key]; !x.newest.IsZero() {
+			m.sample("hibp_target_newest_breach_timestamp_seconds", unix(x.newest), x.labels()...)
+		}
+	}
+	m.family("hibp_target_data_class_breaches", "gauge", "Number of breaches of a watched account or domain exposing a data class.")
+	for _, key := range keys {
+		x := snap.targets[key]
+		classes := make([]string, 0, len(x.dataClasses))
+		for class := range x.dataClasses {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+		for _, class := range classes {
+			m.sample("hibp_target_data_class_breaches", float64(x.dataClasses[class]), append(x.labels(), label{"data_class", class})...)
+		}
+	}
+	return m.end()
+}
+
+func (x *exposure) labels() []label {
+	return []label{{"kind", x.kind}, {"target", x.target}}
+}
+
+func unix(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.Unix())
+}
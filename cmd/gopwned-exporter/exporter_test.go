@@ -0,0 +1,104 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	gopwned "github.com/mavjs/goPwned"
+	"github.com/mavjs/goPwned/hibptest"
+)
+
+func setupExporter(t *testing.T) (*hibptest.Server, *exporter) {
+	srv := hibptest.NewServer()
+	t.Cleanup(srv.Close)
+
+	client := gopwned.NewClient(nil, hibptest.APIKey)
+	client.BaseURL, _ = url.Parse(srv.BaseURL())
+
+	e := newExporter(client,
+		[]string{"multiple-breaches@" + hibptest.Domain, "opt-out@" + hibptest.Domain},
+		[]string{hibptest.Domain})
+	e.logger.SetOutput(ioutil.Discard)
+	return srv, e
+}
+
+func scrape(t *testing.T, e *exporter) string {
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, openMetricsType, rec.Header().Get("Content-Type"))
+	return rec.Body.String()
+}
+
+func TestExporterMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	_, e := setupExporter(t)
+
+	body := scrape(t, e)
+	assert.Equal("# TYPE hibp_exporter_refresh_errors counter\n"+
+		"# HELP hibp_exporter_refresh_errors Failed requests while refreshing.\n"+
+		"hibp_exporter_refresh_errors_total 0\n"+
+		"# EOF\n", body, "[TestExporterMetrics] Expected only the error counter before the first refresh.")
+
+	if err := e.refresh(); err != nil {
+		t.Fatalf("[TestExporterMetrics] refresh returned error: %v", err)
+	}
+	body = scrape(t, e)
+
+	for _, line := range []string{
+		"hibp_breaches 5",
+		"hibp_latest_breach_added_timestamp_seconds 1504034756",
+		`hibp_target_breached_aliases{kind="account",target="multiple-breaches@hibp-integration-tests.com"} 1`,
+		`hibp_target_breached_aliases{kind="account",target="opt-out@hibp-integration-tests.com"} 0`,
+		`hibp_target_breaches{kind="account",target="multiple-breaches@hibp-integration-tests.com"} 3`,
+		`hibp_target_newest_breach_timestamp_seconds{kind="account",target="multiple-breaches@hibp-integration-tests.com"} 1380844800`,
+		`hibp_target_data_class_breaches{kind="account",target="multiple-breaches@hibp-integration-tests.com",data_class="Passwords"} 3`,
+		`hibp_target_data_class_breaches{kind="account",target="multiple-breaches@hibp-integration-tests.com",data_class="Credit cards"} 1`,
+		`hibp_target_breached_aliases{kind="domain",target="hibp-integration-tests.com"} 7`,
+		`hibp_target_breaches{kind="domain",target="hibp-integration-tests.com"} 5`,
+	} {
+		assert.Contains(body, line+"\n", "[TestExporterMetrics] Missing sample.")
+	}
+	assert.NotContains(body, `newest_breach_timestamp_seconds{kind="account",target="opt-out`, "[TestExporterMetrics] Expected no date for accounts without breaches.")
+	assert.True(strings.HasSuffix(body, "# EOF\n"))
+}
+
+func TestExporterFailure(t *testing.T) {
+	assert := assert.New(t)
+
+	srv, e := setupExporter(t)
+	if err := e.refresh(); err != nil {
+		t.Fatalf("[TestExporterFailure] refresh returned error: %v", err)
+	}
+
+	srv.Fail("/api/v3/breacheddomain/", hibptest.Fault{Status: http.StatusServiceUnavailable})
+	assert.Error(e.refresh(), "[TestExporterFailure] Expected the failed domain to be reported.")
+
+	body := scrape(t, e)
+	assert.Contains(body, "hibp_exporter_refresh_errors_total 1\n")
+	assert.Contains(body, `hibp_target_breached_aliases{kind="domain",target="hibp-integration-tests.com"} 7`+"\n", "[TestExporterFailure] Expected the previous figures of a failed target.")
+}
+
+func TestMinInterval(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(time.Duration(0), minInterval(0, 10))
+	assert.Equal(30*time.Second, minInterval(5, 10))
+	assert.Equal(3*time.Minute, minInterval(30, 10))
+}
+
+func TestLabelEscaping(t *testing.T) {
+	var b strings.Builder
+	m := &metricWriter{w: &b}
+	m.sample("metric", 1.5, label{"target", "a\"b\\c\nd"})
+	assert.Equal(t, `metric{target="a\"b\\c\nd"} 1.5`+"\n", b.String(), "[TestLabelEscaping] Expected label values to be escaped.")
+}
@@ -0,0 +1,81 @@
+// Command gopwned-exporter serves exposure figures of watched accounts and
+// domains as Prometheus metrics, in the OpenMetrics text format.
+//
+// Usage:
+//
+//	HIBP_API_KEY=... gopwned-exporter -addr :9119 -domains example.com -accounts alice@example.org [-interval 1h] [-rpm 10]
+//
+// Metrics are served at "/metrics". Per watched account or domain they hold
+// the number of breached aliases and breaches, the date of the newest breach
+// and the number of breaches per data class; globally they hold the number
+// of breaches in the catalogue and the time the latest one was added. The
+// figures are refreshed every -interval, which is raised if needed so a
+// refresh stays within the -rpm rate limit of the API key.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	gopwned "github.com/mavjs/goPwned"
+)
+
+func main() {
+	addr := flag.String("addr", ":9119", "address to listen on")
+	accounts := flag.String("accounts", "", "comma separated accounts to watch")
+	domains := flag.String("domains", "", "comma separated domains to watch, verified for the API key")
+	interval := flag.Duration("interval", time.Hour, "time between refreshes")
+	rpm := flag.Int("rpm", 10, "requests per minute allowed by the API key")
+	flag.Parse()
+
+	client := gopwned.NewClient(nil, os.Getenv("HIBP_API_KEY"))
+	client.Limiter = gopwned.NewLimiter(*rpm)
+
+	e := newExporter(client, split(*accounts), split(*domains))
+	if client.Token == "" && len(e.accounts)+len(e.domains) > 0 {
+		log.Fatal("HIBP_API_KEY must be set to watch accounts or domains")
+	}
+
+	if min := minInterval(len(e.accounts)+len(e.domains), *rpm); *interval < min {
+		log.Printf("raising the interval to %s to stay within %d requests per minute", min, *rpm)
+		*interval = min
+	}
+	go e.run(context.Background(), *interval)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", e)
+
+	srv := &http.Server{
+		Addr:         *addr,
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  2 * time.Minute,
+	}
+	log.Printf("serving metrics on %s", *addr)
+	log.Fatal(srv.ListenAndServe())
+}
+
+// minInterval returns the shortest interval in which n authenticated
+// requests fit within rpm.
+func minInterval(n, rpm int) time.Duration {
+	if rpm <= 0 || n == 0 {
+		return 0
+	}
+	return time.Duration(n) * time.Minute / time.Duration(rpm)
+}
+
+func split(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
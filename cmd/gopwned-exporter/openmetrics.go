@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type (
+	// metricWriter writes metric families in the OpenMetrics text format.
+	// The first write error is kept and returned by err.
+	metricWriter struct {
+		w       io.Writer
+		failure error
+	}
+
+	// label is a single name="value" pair of a sample.
+	label struct {
+		name, value string
+	}
+)
+
+// openMetricsType is the content type of the OpenMetrics text format.
+const openMetricsType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func (m *metricWriter) printf(format string, args ...interface{}) {
+	if m.failure == nil {
+		_, m.failure = fmt.Fprintf(m.w, format, args...)
+	}
+}
+
+// family starts a metric family of typ, "gauge" or "counter".
+func (m *metricWriter) family(name, typ, help string) {
+	m.printf("# TYPE %s %s\n# HELP %s %s\n", name, typ, name, help)
+}
+
+// sample writes a single sample of name.
+func (m *metricWriter) sample(name string, value float64, labels ...label) {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(l.name + `="` + labelEscaper.Replace(l.value) + `"`)
+		}
+		b.WriteByte('}')
+	}
+	m.printf("%s %s\n", b.String(), strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// end terminates the exposition.
+func (m *metricWriter) end() error {
+	m.printf("# EOF\n")
+	return m.failure
+}
@@ -0,0 +1,64 @@
+// Command gopwned-proxy is a caching reverse proxy for the HIBP v3 API, so
+// many internal services can share a single API key and its rate limit.
+//
+// Usage:
+//
+//	HIBP_API_KEY=... gopwned-proxy -addr :8080 [-upstream https://haveibeenpwned.com/api/v3/] [-rpm 10]
+//
+// The proxy serves the same paths as the upstream API below "/api/v3/" and
+// sends them on with the shared "hibp-api-key" and User-Agent; keys sent by
+// clients are ignored. Catalogue endpoints such as breaches and dataclasses
+// are cached, identical in-flight requests are coalesced into a single
+// upstream call, and requests to authenticated endpoints are queued fairly
+// between clients so that together they stay within -rpm. Clients identify
+// themselves with the "X-Gopwned-Client" header, or else by IP address, and
+// their usage is reported as JSON at "/usage".
+//
+// Internal clients only have to point `BaseURL` of a `gopwned.Client` at
+// the proxy, e.g. "http://localhost:8080/api/v3/".
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	gopwned "github.com/mavjs/goPwned"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	upstream := flag.String("upstream", "https://haveibeenpwned.com/api/v3/", "base URL of the HIBP API")
+	rpm := flag.Int("rpm", 10, "requests per minute allowed by the API key")
+	flag.Parse()
+
+	apiKey := os.Getenv("HIBP_API_KEY")
+	if apiKey == "" {
+		log.Fatal("HIBP_API_KEY must be set")
+	}
+	base, err := url.Parse(*upstream)
+	if err != nil {
+		log.Fatalf("invalid upstream URL: %v", err)
+	}
+
+	p := newProxy(base, apiKey, gopwned.NewClient(nil, "").UserAgent, gopwned.NewLimiter(*rpm))
+	go p.queue.Run(context.Background())
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/v3/", http.StripPrefix("/api/v3", p))
+	mux.Handle("/usage", p.usage)
+
+	srv := &http.Server{
+		Addr:         *addr,
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 5 * time.Minute,
+		IdleTimeout:  2 * time.Minute,
+	}
+	log.Printf("proxying %s on %s", base, *addr)
+	log.Fatal(srv.ListenAndServe())
+}
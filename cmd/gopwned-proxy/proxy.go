@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	gopwned "github.com/mavjs/goPwned"
+)
+
+type (
+	// proxy forwards HIBP v3 requests upstream with a shared API key and
+	// User-Agent. Catalogue responses are cached, identical in-flight
+	// requests are coalesced, and authenticated requests wait in a fair
+	// queue so all clients together stay within the key's rate limit.
+	proxy struct {
+		upstream  *url.URL
+		apiKey    string
+		userAgent string
+		client    *http.Client
+
+		cache    gopwned.Cache
+		cacheTTL map[string]time.Duration
+		queue    *fairQueue
+		usage    *usage
+		flights  flightGroup
+	}
+
+	// result is an upstream response shared between coalesced callers.
+	result struct {
+		status int
+		header http.Header
+		body   []byte
+	}
+
+	flight struct {
+		done    chan struct{}
+		res     *result
+		queued  time.Duration
+		err     error
+		waiters int
+		cancel  context.CancelFunc
+	}
+
+	// flightGroup runs a single upstream request per key at a time and
+	// shares its result with every caller asking for the same key meanwhile.
+	flightGroup struct {
+		mu      sync.Mutex
+		flights map[string]*flight
+	}
+)
+
+// statusClientClosed is recorded for callers that went away before their
+// response was ready, as nginx does.
+const statusClientClosed = 499
+
+// ClientHeader identifies the calling service for usage accounting. Clients
+// without it are accounted by their IP address.
+const ClientHeader = "X-Gopwned-Client"
+
+var (
+	// defaultProxyTTL - how long catalogue responses are served from cache.
+	defaultProxyTTL = map[string]time.Duration{
+		"breaches":     6 * time.Hour,
+		"breach":       6 * time.Hour,
+		"dataclasses":  24 * time.Hour,
+		"latestbreach": 5 * time.Minute,
+	}
+
+	// authenticatedGroups - endpoints that need the API key and count
+	// against its rate limit.
+	authenticatedGroups = map[string]bool{
+		"breachedaccount":   true,
+		"pasteaccount":      true,
+		"breacheddomain":    true,
+		"subscribeddomains": true,
+		"subscription":      true,
+	}
+
+	// forwardedHeaders - upstream response headers passed on to clients.
+	forwardedHeaders = []string{"Content-Type", "Retry-After", "ETag", "Last-Modified", "CF-Ray"}
+)
+
+// do calls fn once for every key that is not already in flight, on a
+// context detached from the callers' requests, so a caller going away does
+// not fail the others. Callers arriving while it runs get the same result,
+// with shared set to true. Each caller stops waiting once its ctx is done,
+// and the flight is canceled when no caller is left waiting for it. fn
+// returns how long the flight waited in the queue along with its result.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(ctx context.Context) (*result, time.Duration, error)) (res *result, queued time.Duration, err error, shared bool) {
+	g.mu.Lock()
+	if g.flights == nil {
+		g.flights = make(map[string]*flight)
+	}
+	f, shared := g.flights[key]
+	if !shared {
+		fctx, cancel := context.WithCancel(context.Background())
+		f = &flight{done: make(chan struct{}), cancel: cancel}
+		g.flights[key] = f
+		go g.run(key, f, fctx, fn)
+	}
+	f.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.res, f.queued, f.err, shared
+	case <-ctx.Done():
+		g.mu.Lock()
+		f.waiters--
+		if f.waiters == 0 {
+			f.cancel()
+			g.forget(key, f)
+		}
+		g.mu.Unlock()
+		return nil, 0, ctx.Err(), shared
+	}
+}
+
+func (g *flightGroup) run(key string, f *flight, ctx context.Context, fn func(ctx context.Context) (*result, time.Duration, error)) {
+	f.res, f.queued, f.err = fn(ctx)
+
+	g.mu.Lock()
+	g.forget(key, f)
+	g.mu.Unlock()
+	f.cancel()
+	close(f.done)
+}
+
+// forget removes f from the group, unless a new flight has replaced it.
+// g.mu must be held.
+func (g *flightGroup) forget(key string, f *flight) {
+	if g.flights[key] == f {
+		delete(g.flights, key)
+	}
+}
+
+// clientName returns who is calling, for accounting and fair queuing.
+func clientName(r *http.Request) string {
+	if name := r.Header.Get(ClientHeader); name != "" {
+		return name
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// group returns the first path segment below the API root.
+func group(resource string) string {
+	if i := strings.IndexByte(resource, '/'); i >= 0 {
+		return resource[:i]
+	}
+	return resource
+}
+
+func (p *proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	client := clientName(r)
+	resource := strings.TrimPrefix(r.URL.EscapedPath(), "/")
+	target, err := p.upstream.Parse(resource)
+	if err != nil || !p.within(target) {
+		http.Error(w, "invalid path", http.StatusBadRequest)
+		return
+	}
+	target.RawQuery = r.URL.RawQuery
+	key := target.String()
+
+	grp := group(resource)
+	ttl := p.cacheTTL[grp]
+	if ttl > 0 {
+		if entry, ok := p.cache.Get(key); ok && time.Now().Before(entry.Expires) {
+			p.usage.record(client, fromCache, http.StatusOK, 0)
+			writeResult(w, &result{status: http.StatusOK, header: cachedHeader(entry), body: entry.Body})
+			return
+		}
+	}
+
+	res, queued, err, shared := p.flights.do(r.Context(), key, func(ctx context.Context) (*result, time.Duration, error) {
+		var queued time.Duration
+		if authenticatedGroups[grp] {
+			start := time.Now()
+			if err := p.queue.Wait(ctx, client); err != nil {
+				return nil, 0, err
+			}
+			queued = time.Since(start)
+		}
+
+		res, err := p.fetch(ctx, key)
+		if err == nil && ttl > 0 && res.status == http.StatusOK {
+			p.cache.Set(key, &gopwned.CacheEntry{
+				Body:         res.body,
+				ETag:         res.header.Get("ETag"),
+				LastModified: res.header.Get("Last-Modified"),
+				Expires:      time.Now().Add(ttl),
+			})
+		}
+		return res, queued, err
+	})
+	if err != nil && r.Context().Err() != nil {
+		p.usage.record(client, fromUpstream, statusClientClosed, 0)
+		return
+	}
+	if err != nil {
+		p.usage.record(client, fromUpstream, http.StatusBadGateway, queued)
+		http.Error(w, "upstream request failed", http.StatusBadGateway)
+		return
+	}
+
+	how := fromUpstream
+	if shared {
+		how = fromCoalesced
+	}
+	p.usage.record(client, how, res.status, queued)
+	writeResult(w, res)
+}
+
+// within reports whether target is below the directory of the upstream API,
+// so a crafted path cannot send the shared key to another host or endpoint.
+// Dot segments that survived escaping are resolved on the decoded path.
+func (p *proxy) within(target *url.URL) bool {
+	root := p.upstream.EscapedPath()
+	root = root[:strings.LastIndexByte(root, '/')+1]
+	return target.Scheme == p.upstream.Scheme && target.Host == p.upstream.Host &&
+		target.Opaque == "" && target.User == nil &&
+		strings.HasPrefix(target.EscapedPath(), root) && strings.HasPrefix(path.Clean(target.Path)+"/", root)
+}
+
+// fetch sends the request upstream with the shared key and User-Agent.
+func (p *proxy) fetch(ctx context.Context, target string) (*result, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", p.userAgent)
+	req.Header.Set("hibp-api-key", p.apiKey)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	for _, h := range forwardedHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			header.Set(h, v)
+		}
+	}
+	return &result{status: resp.StatusCode, header: header, body: body}, nil
+}
+
+func cachedHeader(entry *gopwned.CacheEntry) http.Header {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	if entry.ETag != "" {
+		header.Set("ETag", entry.ETag)
+	}
+	if entry.LastModified != "" {
+		header.Set("Last-Modified", entry.LastModified)
+	}
+	return header
+}
+
+func writeResult(w http.ResponseWriter, res *result) {
+	for k, v := range res.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(res.status)
+	w.Write(res.body)
+}
+
+func newProxy(upstream *url.URL, apiKey, userAgent string, limiter *gopwned.Limiter) *proxy {
+	return &proxy{
+		upstream:  upstream,
+		apiKey:    apiKey,
+		userAgent: userAgent,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		cache:     gopwned.NewMemoryCache(1024),
+		cacheTTL:  defaultProxyTTL,
+		queue:     newFairQueue(limiter),
+		usage:     newUsage(),
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	gopwned "github.com/mavjs/goPwned"
+)
+
+type upstream struct {
+	calls   int64
+	release chan struct{}
+	headers chan http.Header
+}
+
+func (u *upstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&u.calls, 1)
+	select {
+	case u.headers <- r.Header.Clone():
+	default:
+	}
+	if u.release != nil {
+		<-u.release
+	}
+
+	switch r.URL.Path {
+	case "/api/v3/breaches", "/api/v3/breachedaccount/foo@example.com":
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, `[{"Name":"Adobe"}]`)
+	default:
+		w.Header().Set("Retry-After", "2")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}
+}
+
+func setupProxy(t *testing.T, up *upstream) (*proxy, *httptest.Server) {
+	upServer := httptest.NewServer(up)
+	t.Cleanup(upServer.Close)
+
+	base, _ := url.Parse(upServer.URL + "/api/v3/")
+	p := newProxy(base, "shared-key", "gopwned-test", gopwned.NewLimiter(0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go p.queue.Run(ctx)
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/v3/", http.StripPrefix("/api/v3", p))
+	mux.Handle("/usage", p.usage)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return p, server
+}
+
+func get(t *testing.T, target, client string) (*http.Response, string) {
+	req, _ := http.NewRequest("GET", target, nil)
+	req.Header.Set("hibp-api-key", "client-key")
+	if client != "" {
+		req.Header.Set(ClientHeader, client)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unable to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	return resp, string(body)
+}
+
+func TestProxyHeaders(t *testing.T) {
+	assert := assert.New(t)
+
+	up := &upstream{headers: make(chan http.Header, 1)}
+	_, server := setupProxy(t, up)
+
+	resp, body := get(t, server.URL+"/api/v3/breachedaccount/foo@example.com", "web")
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal(`[{"Name":"Adobe"}]`, body)
+
+	header := <-up.headers
+	assert.Equal("shared-key", header.Get("hibp-api-key"), "[TestProxyHeaders] Expected the shared key instead of the client's.")
+	assert.Equal("gopwned-test", header.Get("User-Agent"))
+}
+
+func TestProxyCache(t *testing.T) {
+	assert := assert.New(t)
+
+	up := &upstream{}
+	p, server := setupProxy(t, up)
+
+	for i := 0; i < 3; i++ {
+		resp, body := get(t, server.URL+"/api/v3/breaches", "web")
+		assert.Equal(http.StatusOK, resp.StatusCode)
+		assert.Equal(`[{"Name":"Adobe"}]`, body)
+		assert.Equal(`"v1"`, resp.Header.Get("ETag"))
+	}
+	assert.Equal(int64(1), atomic.LoadInt64(&up.calls), "[TestProxyCache] Expected catalogue responses to be cached.")
+
+	get(t, server.URL+"/api/v3/breachedaccount/foo@example.com", "web")
+	get(t, server.URL+"/api/v3/breachedaccount/foo@example.com", "web")
+	assert.Equal(int64(3), atomic.LoadInt64(&up.calls), "[TestProxyCache] Expected account responses not to be cached.")
+
+	usage := p.usage.snapshot()["web"]
+	assert.Equal(int64(5), usage.Requests)
+	assert.Equal(int64(2), usage.CacheHits)
+	assert.Equal(int64(3), usage.Upstream)
+}
+
+func TestProxyCoalesce(t *testing.T) {
+	assert := assert.New(t)
+
+	up := &upstream{release: make(chan struct{})}
+	p, server := setupProxy(t, up)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, _ := get(t, server.URL+"/api/v3/breachedaccount/foo@example.com", "web")
+			assert.Equal(http.StatusOK, resp.StatusCode)
+		}()
+	}
+
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		p.flights.mu.Lock()
+		n := len(p.flights.flights)
+		p.flights.mu.Unlock()
+		if n == 1 && atomic.LoadInt64(&up.calls) == 1 {
+			break
+		}
+	}
+	// Give the other requests time to join the flight.
+	time.Sleep(50 * time.Millisecond)
+	close(up.release)
+	wg.Wait()
+
+	usage := p.usage.snapshot()["web"]
+	assert.Equal(int64(5), usage.Requests)
+	assert.Equal(atomic.LoadInt64(&up.calls), usage.Upstream, "[TestProxyCoalesce] Expected a single upstream call per flight.")
+	assert.Equal(int64(5), usage.Upstream+usage.Coalesced)
+	assert.True(usage.Coalesced > 0, "[TestProxyCoalesce] Expected identical requests to be coalesced.")
+}
+
+func TestProxyLeaderCancel(t *testing.T) {
+	assert := assert.New(t)
+
+	up := &upstream{release: make(chan struct{})}
+	p, server := setupProxy(t, up)
+	target := server.URL + "/api/v3/breachedaccount/foo@example.com"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error)
+	go func() {
+		req, _ := http.NewRequestWithContext(ctx, "GET", target, nil)
+		req.Header.Set(ClientHeader, "leader")
+		resp, err := http.DefaultClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		leader <- err
+	}()
+	waitWaiters(t, p, 1)
+
+	follower := make(chan *http.Response)
+	go func() {
+		resp, _ := get(t, target, "follower")
+		follower <- resp
+	}()
+	waitWaiters(t, p, 2)
+
+	cancel()
+	assert.Error(<-leader, "[TestProxyLeaderCancel] Expected the leader request to be canceled.")
+	close(up.release)
+
+	resp := <-follower
+	assert.Equal(http.StatusOK, resp.StatusCode, "[TestProxyLeaderCancel] Expected the follower to get the shared response.")
+	assert.Equal(int64(1), atomic.LoadInt64(&up.calls), "[TestProxyLeaderCancel] Expected a single upstream call.")
+}
+
+// waitWaiters waits until n callers wait for the single flight in progress.
+func waitWaiters(t *testing.T, p *proxy, n int) {
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		p.flights.mu.Lock()
+		waiters := 0
+		for _, f := range p.flights.flights {
+			waiters += f.waiters
+		}
+		p.flights.mu.Unlock()
+		if waiters == n {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %d callers to join the flight", n)
+}
+
+func TestProxyPassThrough(t *testing.T) {
+	assert := assert.New(t)
+
+	_, server := setupProxy(t, &upstream{})
+
+	resp, _ := get(t, server.URL+"/api/v3/breacheddomain/example.com", "")
+	assert.Equal(http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal("2", resp.Header.Get("Retry-After"), "[TestProxyPassThrough] Expected Retry-After to be passed on.")
+
+	req, _ := http.NewRequest("POST", server.URL+"/api/v3/breaches", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if assert.NoError(err) {
+		resp.Body.Close()
+		assert.Equal(http.StatusMethodNotAllowed, resp.StatusCode)
+	}
+
+	resp, body := get(t, server.URL+"/usage", "")
+	assert.Equal(http.StatusOK, resp.StatusCode)
+
+	var usage map[string]clientUsage
+	if assert.NoError(json.Unmarshal([]byte(body), &usage)) {
+		assert.Equal(int64(1), usage["127.0.0.1"].Statuses["429"], "[TestProxyPassThrough] Expected clients without a name to be accounted by IP.")
+	}
+}
+
+func TestProxyClient(t *testing.T) {
+	assert := assert.New(t)
+
+	_, server := setupProxy(t, &upstream{})
+
+	client := gopwned.NewClient(nil, "")
+	client.BaseURL, _ = url.Parse(server.URL + "/api/v3/")
+
+	breaches, err := client.GetBreachedSites("")
+	if assert.NoError(err) && assert.Len(breaches, 1) {
+		assert.Equal("Adobe", breaches[0].Name, "[TestProxyClient] Expected clients to work against the proxy.")
+	}
+}
+
+func TestProxyRejectsForeignTargets(t *testing.T) {
+	assert := assert.New(t)
+
+	up := &upstream{}
+	p, _ := setupProxy(t, up)
+
+	for _, path := range []string{"/http:evil.com/x", "///evil.com/x", "/../../x", "/%2e%2e/%2e%2e/x"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.URL.Path = path
+		if unescaped, err := url.PathUnescape(path); err == nil && unescaped != path {
+			req.URL.Path, req.URL.RawPath = unescaped, path
+		}
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, req)
+		assert.Equal(http.StatusBadRequest, rec.Code, "[TestProxyRejectsForeignTargets] Expected %s to be rejected.", path)
+	}
+	assert.Equal(int64(0), atomic.LoadInt64(&up.calls), "[TestProxyRejectsForeignTargets] Expected nothing to be sent upstream.")
+
+	req := httptest.NewRequest("GET", "/breaches", nil)
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, req)
+	assert.Equal(http.StatusOK, rec.Code, "[TestProxyRejectsForeignTargets] Expected paths below the API root to be forwarded.")
+}
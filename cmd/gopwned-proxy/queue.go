@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	gopwned "github.com/mavjs/goPwned"
+)
+
+type (
+	// fairQueue hands out the slots of a rate limiter to waiting callers,
+	// round-robin between clients, so a single busy client cannot starve the
+	// others. Requests of the same client are served in arrival order.
+	fairQueue struct {
+		limiter *gopwned.Limiter
+
+		mu      sync.Mutex
+		pending map[string][]*ticket
+		ring    []string // clients with pending tickets, in serving order
+		wake    chan struct{}
+	}
+
+	ticket struct {
+		ready    chan struct{}
+		canceled bool
+	}
+)
+
+func newFairQueue(limiter *gopwned.Limiter) *fairQueue {
+	return &fairQueue{
+		limiter: limiter,
+		pending: make(map[string][]*ticket),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Wait blocks until client may send its request, or until ctx is done.
+func (q *fairQueue) Wait(ctx context.Context, client string) error {
+	t := &ticket{ready: make(chan struct{})}
+
+	q.mu.Lock()
+	if len(q.pending[client]) == 0 {
+		q.ring = append(q.ring, client)
+	}
+	q.pending[client] = append(q.pending[client], t)
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+
+	select {
+	case <-t.ready:
+		return nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		t.canceled = true
+		q.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Run dispatches slots until ctx is done.
+func (q *fairQueue) Run(ctx context.Context) {
+	for {
+		t := q.next()
+		if t == nil {
+			select {
+			case <-q.wake:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if err := q.limiter.Wait(ctx); err != nil {
+			return
+		}
+
+		q.mu.Lock()
+		if !t.canceled {
+			close(t.ready)
+		}
+		q.mu.Unlock()
+	}
+}
+
+// next pops the oldest live ticket of the next client in the ring.
+func (q *fairQueue) next() *ticket {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.ring) > 0 {
+		client := q.ring[0]
+		q.ring = q.ring[1:]
+
+		tickets := q.pending[client]
+		t := tickets[0]
+		if len(tickets) > 1 {
+			q.pending[client] = tickets[1:]
+			q.ring = append(q.ring, client)
+		} else {
+			delete(q.pending, client)
+		}
+
+		if !t.canceled {
+			return t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	gopwned "github.com/mavjs/goPwned"
+)
+
+// enqueue starts a waiter for client and returns once its ticket is queued.
+func enqueue(t *testing.T, q *fairQueue, client string, served chan<- string) {
+	q.mu.Lock()
+	before := len(q.pending[client])
+	q.mu.Unlock()
+
+	go func() {
+		if err := q.Wait(context.Background(), client); err == nil {
+			served <- client
+		}
+	}()
+
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		q.mu.Lock()
+		n := len(q.pending[client])
+		q.mu.Unlock()
+		if n > before {
+			return
+		}
+	}
+	t.Fatalf("ticket of %s was not queued", client)
+}
+
+func TestFairQueueRoundRobin(t *testing.T) {
+	assert := assert.New(t)
+
+	q := newFairQueue(gopwned.NewLimiter(6000))
+	served := make(chan string, 4)
+
+	enqueue(t, q, "batch", served)
+	enqueue(t, q, "batch", served)
+	enqueue(t, q, "batch", served)
+	enqueue(t, q, "web", served)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.Run(ctx)
+
+	var order []string
+	for i := 0; i < 4; i++ {
+		select {
+		case client := <-served:
+			order = append(order, client)
+		case <-time.After(time.Second):
+			t.Fatalf("[TestFairQueueRoundRobin] only %d of 4 requests were served", i)
+		}
+	}
+	assert.Equal([]string{"batch", "web", "batch", "batch"}, order, "[TestFairQueueRoundRobin] Expected clients to take turns.")
+}
+
+func TestFairQueueCancel(t *testing.T) {
+	assert := assert.New(t)
+
+	q := newFairQueue(gopwned.NewLimiter(6000))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(context.DeadlineExceeded, q.Wait(ctx, "web"), "[TestFairQueueCancel] Expected the context error while the queue is not running.")
+	assert.Nil(q.next(), "[TestFairQueueCancel] Expected canceled tickets to be skipped.")
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type (
+	// clientUsage counts what a single client did through the proxy.
+	clientUsage struct {
+		Requests  int64            `json:"requests"`
+		CacheHits int64            `json:"cache_hits"`
+		Coalesced int64            `json:"coalesced"`
+		Upstream  int64            `json:"upstream"`
+		Queued    time.Duration    `json:"queued_ns"`
+		Statuses  map[string]int64 `json:"statuses"`
+	}
+
+	// usage keeps per-client accounting. It is safe for concurrent use.
+	usage struct {
+		mu      sync.Mutex
+		clients map[string]*clientUsage
+	}
+
+	// outcome is how a single request was answered.
+	outcome int
+)
+
+const (
+	fromUpstream outcome = iota
+	fromCache
+	fromCoalesced
+)
+
+func newUsage() *usage {
+	return &usage{clients: make(map[string]*clientUsage)}
+}
+
+// record accounts a finished request of client.
+func (u *usage) record(client string, how outcome, status int, queued time.Duration) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	c, ok := u.clients[client]
+	if !ok {
+		c = &clientUsage{Statuses: make(map[string]int64)}
+		u.clients[client] = c
+	}
+
+	c.Requests++
+	switch how {
+	case fromCache:
+		c.CacheHits++
+	case fromCoalesced:
+		c.Coalesced++
+	default:
+		c.Upstream++
+	}
+	c.Queued += queued
+	c.Statuses[strconv.Itoa(status)]++
+}
+
+// snapshot returns a copy of the usage of every client.
+func (u *usage) snapshot() map[string]clientUsage {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	snap := make(map[string]clientUsage, len(u.clients))
+	for name, c := range u.clients {
+		cp := *c
+		cp.Statuses = make(map[string]int64, len(c.Statuses))
+		for k, v := range c.Statuses {
+			cp.Statuses[k] = v
+		}
+		snap[name] = cp
+	}
+	return snap
+}
+
+// ServeHTTP reports the usage of every client as JSON.
+func (u *usage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(u.snapshot())
+}
@@ -0,0 +1,46 @@
+// Command gopwned-rangeserver serves a local copy of the Pwned Passwords
+// corpus through the same range API as api.pwnedpasswords.com, for networks
+// without internet access.
+//
+// Usage:
+//
+//	gopwned-rangeserver -addr :8080 -sha1 /srv/pwnedpasswords/sha1 [-ntlm /srv/pwnedpasswords/ntlm]
+//
+// Each corpus directory holds a file per hash prefix, named "<PREFIX>.txt",
+// with one "SUFFIX:COUNT" line per hash, as written by the Pwned Passwords
+// downloader. Responses are byte-for-byte the same as the upstream API,
+// including "Add-Padding" support, so existing clients only have to point
+// their range URL at this server, e.g. by setting `PwnPwdURL` of a
+// `gopwned.Client` to "http://localhost:8080/range/".
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	sha1Dir := flag.String("sha1", "", "directory holding the SHA-1 range files")
+	ntlmDir := flag.String("ntlm", "", "directory holding the NTLM range files, served with ?mode=ntlm")
+	flag.Parse()
+
+	if *sha1Dir == "" {
+		log.Fatal("the -sha1 corpus directory is required")
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/range/", newServer(*sha1Dir, *ntlmDir))
+
+	srv := &http.Server{
+		Addr:         *addr,
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  2 * time.Minute,
+	}
+	log.Printf("serving ranges from %s on %s", *sha1Dir, *addr)
+	log.Fatal(srv.ListenAndServe())
+}
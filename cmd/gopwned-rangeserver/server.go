@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// server serves `GET /range/{prefix}` from corpus directories holding a
+	// file per hash prefix, as written by the Pwned Passwords downloader.
+	server struct {
+		sha1Dir string
+		ntlmDir string
+
+		mu  sync.Mutex
+		rnd *rand.Rand
+	}
+
+	// hashMode describes one of the hash types served by the range API.
+	hashMode struct {
+		dir       string
+		suffixLen int
+	}
+)
+
+const (
+	// cacheControl - the caching policy of api.pwnedpasswords.com.
+	cacheControl = "public, max-age=2678400"
+
+	// minPadding and maxPadding bound the number of lines of a padded
+	// response, the same as the upstream API.
+	minPadding = 800
+	maxPadding = 1000
+)
+
+func newServer(sha1Dir, ntlmDir string) *server {
+	return &server{sha1Dir: sha1Dir, ntlmDir: ntlmDir, rnd: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	prefix := strings.TrimPrefix(r.URL.Path, "/range/")
+	if prefix == r.URL.Path || !validPrefix(prefix) {
+		http.Error(w, "The hash prefix was not in a valid format", http.StatusBadRequest)
+		return
+	}
+	prefix = strings.ToUpper(prefix)
+
+	mode := hashMode{dir: s.sha1Dir, suffixLen: 35}
+	if strings.EqualFold(r.URL.Query().Get("mode"), "ntlm") {
+		if s.ntlmDir == "" {
+			http.Error(w, "NTLM mode is not available", http.StatusBadRequest)
+			return
+		}
+		mode = hashMode{dir: s.ntlmDir, suffixLen: 27}
+	}
+
+	body, modTime, err := readRange(mode.dir, prefix)
+	if os.IsNotExist(err) {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", "text/plain")
+	header.Set("Cache-Control", cacheControl)
+	header.Set("Vary", "Add-Padding")
+	header.Set("Access-Control-Allow-Origin", "*")
+
+	if strings.EqualFold(r.Header.Get("Add-Padding"), "true") {
+		// Padded bodies differ on every request, so they carry no validators.
+		body = s.pad(body, mode.suffixLen)
+		header.Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+		header.Set("Content-Length", strconv.Itoa(len(body)))
+		if r.Method == http.MethodGet {
+			w.Write(body)
+		}
+		return
+	}
+
+	sum := sha1.Sum(body)
+	header.Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+	http.ServeContent(w, r, "", modTime, bytes.NewReader(body))
+}
+
+// validPrefix reports whether prefix is 5 hexadecimal characters.
+func validPrefix(prefix string) bool {
+	if len(prefix) != 5 {
+		return false
+	}
+	_, err := hex.DecodeString(prefix + "0")
+	return err == nil
+}
+
+// readRange loads the range file of prefix and normalizes it to the format
+// of the upstream API: "SUFFIX:COUNT" lines separated by CRLF, without a
+// trailing line break.
+func readRange(dir, prefix string) ([]byte, time.Time, error) {
+	path := filepath.Join(dir, prefix+".txt")
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	lines := strings.Fields(string(data))
+	return []byte(strings.Join(lines, "\r\n")), info.ModTime(), nil
+}
+
+// pad adds lines with random suffixes and a count of 0 until the body holds
+// between minPadding and maxPadding lines, keeping the lines sorted.
+func (s *server) pad(body []byte, suffixLen int) []byte {
+	lines := strings.Split(string(body), "\r\n")
+	if len(body) == 0 {
+		lines = nil
+	}
+
+	seen := make(map[string]bool, len(lines))
+	for _, line := range lines {
+		seen[line[:strings.IndexByte(line, ':')+1]] = true
+	}
+
+	s.mu.Lock()
+	target := minPadding + s.rnd.Intn(maxPadding-minPadding+1)
+	for len(lines) < target {
+		suffix := s.randomSuffix(suffixLen) + ":"
+		if seen[suffix] {
+			continue
+		}
+		seen[suffix] = true
+		lines = append(lines, suffix+"0")
+	}
+	s.mu.Unlock()
+
+	sort.Strings(lines)
+	return []byte(strings.Join(lines, "\r\n"))
+}
+
+// randomSuffix must be called with s.mu held.
+func (s *server) randomSuffix(n int) string {
+	const digits = "0123456789ABCDEF"
+
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = digits[s.rnd.Intn(len(digits))]
+	}
+	return string(b)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	gopwned "github.com/mavjs/goPwned"
+)
+
+func setupRangeServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/range/", newServer(filepath.Join("testdata", "sha1"), filepath.Join("testdata", "ntlm")))
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func get(t *testing.T, target string, header map[string]string) (*http.Response, string) {
+	req, err := http.NewRequest("GET", target, nil)
+	if err != nil {
+		t.Fatalf("unable to create request: %v", err)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unable to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	return resp, string(body)
+}
+
+func TestRange(t *testing.T) {
+	assert := assert.New(t)
+
+	server := setupRangeServer(t)
+
+	resp, body := get(t, server.URL+"/range/21bd1", nil)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal("0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n"+
+		"00D4F6E8FA6EECAD2A3AA415EEC418D38EC:2\r\n"+
+		"2DC183F740EE76F27B78EB39C8AD972A757:83129", body, "[TestRange] Expected the upstream format without a trailing line break.")
+	assert.Equal("text/plain", resp.Header.Get("Content-Type"))
+	assert.Equal(cacheControl, resp.Header.Get("Cache-Control"))
+	assert.NotEmpty(resp.Header.Get("Last-Modified"))
+
+	etag := resp.Header.Get("ETag")
+	if assert.NotEmpty(etag) {
+		resp, body = get(t, server.URL+"/range/21BD1", map[string]string{"If-None-Match": etag})
+		assert.Equal(http.StatusNotModified, resp.StatusCode, "[TestRange] Expected a matching ETag to be revalidated.")
+		assert.Empty(body)
+	}
+}
+
+func TestRangeNTLM(t *testing.T) {
+	server := setupRangeServer(t)
+
+	resp, body := get(t, server.URL+"/range/AB1C2?mode=ntlm", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "0034B2F5F8D3E8A2C1B10F3F9F2:3\r\n1C1A6F9F9B0AC8E6A1FDA9D1F1A:42", body, "[TestRangeNTLM] Expected LF line endings to be normalized.")
+}
+
+func TestRangePadding(t *testing.T) {
+	assert := assert.New(t)
+
+	server := setupRangeServer(t)
+
+	resp, body := get(t, server.URL+"/range/21BD1", map[string]string{"Add-Padding": "true"})
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Empty(resp.Header.Get("ETag"), "[TestRangePadding] Expected padded responses not to carry an ETag.")
+
+	lines := strings.Split(body, "\r\n")
+	assert.True(len(lines) >= minPadding && len(lines) <= maxPadding, "[TestRangePadding] Expected between 800 and 1000 lines. Got: %d", len(lines))
+	assert.Contains(lines, "2DC183F740EE76F27B78EB39C8AD972A757:83129")
+
+	padding := 0
+	for _, line := range lines {
+		parts := strings.Split(line, ":")
+		if !assert.Len(parts, 2) {
+			break
+		}
+		assert.Len(parts[0], 35, "[TestRangePadding] Expected SHA-1 suffixes.")
+		if parts[1] == "0" {
+			padding++
+		}
+	}
+	assert.Equal(len(lines)-3, padding)
+}
+
+func TestRangeErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	server := setupRangeServer(t)
+
+	for _, prefix := range []string{"1234G", "123", "123456"} {
+		resp, body := get(t, server.URL+"/range/"+prefix, nil)
+		assert.Equal(http.StatusBadRequest, resp.StatusCode, "[TestRangeErrors] Expected %s to be rejected.", prefix)
+		assert.Equal("The hash prefix was not in a valid format\n", body)
+	}
+
+	resp, _ := get(t, server.URL+"/range/FFFFF", nil)
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+
+	req, _ := http.NewRequest("POST", server.URL+"/range/21BD1", nil)
+	postResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("[TestRangeErrors] unable to send request: %v", err)
+	}
+	postResp.Body.Close()
+	assert.Equal(http.StatusMethodNotAllowed, postResp.StatusCode)
+}
+
+func TestRangeWithClient(t *testing.T) {
+	server := setupRangeServer(t)
+
+	client := gopwned.NewClient(nil, "")
+	client.PwnPwdURL, _ = url.Parse(server.URL + "/range/")
+
+	for _, padding := range []bool{false, true} {
+		body, err := client.GetPwnedPasswords("21BD1", padding)
+		if err != nil {
+			t.Fatalf("[TestRangeWithClient] returned error: %v", err)
+		}
+		assert.Contains(t, string(body), "2DC183F740EE76F27B78EB39C8AD972A757:83129")
+	}
+}
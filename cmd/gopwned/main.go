@@ -0,0 +1,174 @@
+// Command gopwned is a command line client for the haveibeenpwned.com API.
+//
+// Usage:
+//
+//	gopwned watch -config watch.json
+//
+// The watch command runs until interrupted. It polls for new breaches and
+// re-checks the accounts and domains of its watchlist whenever one shows up,
+// sending every new finding to the sinks of its configuration, or printing it
+// as a line of JSON if there are none. The API key is read from the
+// HIBP_API_KEY environment variable.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	gopwned "github.com/mavjs/goPwned"
+	"github.com/mavjs/goPwned/notify"
+	"github.com/mavjs/goPwned/watch"
+)
+
+type (
+	// watchConfig is the layout of the watch configuration file.
+	watchConfig struct {
+		Accounts  []string     `json:"accounts"`
+		Domains   []string     `json:"domains"`
+		Interval  string       `json:"interval"`
+		State     string       `json:"state"`
+		RateLimit int          `json:"rate_limit"`
+		Sinks     []sinkConfig `json:"sinks"`
+	}
+
+	// sinkConfig configures a single notification sink. Which fields are
+	// used depends on its type.
+	sinkConfig struct {
+		Type     string   `json:"type"`
+		URL      string   `json:"url"`
+		Secret   string   `json:"secret"`
+		Network  string   `json:"network"`
+		Addr     string   `json:"addr"`
+		Tag      string   `json:"tag"`
+		From     string   `json:"from"`
+		To       []string `json:"to"`
+		Username string   `json:"username"`
+		Password string   `json:"password"`
+	}
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s watch -config <file> [-once]\n", os.Args[0])
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "watch":
+		if err := runWatch(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		usage()
+	}
+}
+
+func loadWatchConfig(path string) (*watchConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &watchConfig{Interval: watch.DefaultInterval.String(), RateLimit: 10}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// newSink creates the sink described by config.
+func newSink(config sinkConfig) (notify.Sink, error) {
+	switch config.Type {
+	case "stdout":
+		return notify.NewWriter(os.Stdout), nil
+	case "webhook":
+		return &notify.Webhook{URL: config.URL, Secret: config.Secret}, nil
+	case "slack":
+		return &notify.Slack{URL: config.URL}, nil
+	case "teams":
+		return &notify.Teams{URL: config.URL}, nil
+	case "email":
+		sink := &notify.Email{Addr: config.Addr, From: config.From, To: config.To}
+		if config.Username != "" {
+			host, _, err := net.SplitHostPort(config.Addr)
+			if err != nil {
+				return nil, err
+			}
+			sink.Auth = smtp.PlainAuth("", config.Username, config.Password, host)
+		}
+		return sink, nil
+	case "syslog":
+		return &notify.Syslog{Network: config.Network, Addr: config.Addr, Tag: config.Tag}, nil
+	default:
+		return nil, fmt.Errorf("unknown sink type %q", config.Type)
+	}
+}
+
+func runWatch(args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	configPath := fs.String("config", "watch.json", "path of the watch configuration file")
+	once := fs.Bool("once", false, "poll once and exit")
+	fs.Parse(args)
+
+	config, err := loadWatchConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	interval, err := time.ParseDuration(config.Interval)
+	if err != nil {
+		return fmt.Errorf("invalid interval: %w", err)
+	}
+
+	var sinks []notify.Sink
+	for _, sc := range config.Sinks {
+		sink, err := newSink(sc)
+		if err != nil {
+			return err
+		}
+		sinks = append(sinks, sink)
+	}
+	if len(sinks) == 0 {
+		sinks = append(sinks, notify.NewWriter(os.Stdout))
+	}
+
+	client := gopwned.NewClient(nil, os.Getenv("HIBP_API_KEY"))
+	client.Limiter = gopwned.NewLimiter(config.RateLimit)
+
+	w, err := watch.New(client, watch.Config{
+		Accounts: config.Accounts,
+		Domains:  config.Domains,
+		Interval: interval,
+		State:    config.State,
+	}, sinks...)
+	if err != nil {
+		return err
+	}
+
+	if *once {
+		_, err := w.Poll()
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := w.Run(ctx); err != context.Canceled {
+		return err
+	}
+	return nil
+}
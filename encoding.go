@@ -0,0 +1,132 @@
+package gopwned
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+type (
+	// Decoder decompresses range responses of one content encoding. Gzip is
+	// used when a client has no Decoders; others, such as brotli, can be
+	// plugged in without this package depending on them.
+	Decoder interface {
+		// Encoding returns the "Content-Encoding" token handled, e.g. "br".
+		Encoding() string
+		// NewReader returns a reader of the decompressed content of r.
+		NewReader(r io.Reader) (io.ReadCloser, error)
+	}
+
+	gzipDecoder struct{}
+
+	// decodedBody closes both the decompressing reader and the raw body.
+	decodedBody struct {
+		io.ReadCloser
+		raw io.ReadCloser
+	}
+
+	// limitedBody fails reads past its limit with ErrResponseTooLarge.
+	limitedBody struct {
+		io.ReadCloser
+		left int64
+	}
+)
+
+// DefaultMaxRangeSize - the decompressed size a range response may reach
+// when a client has no MaxRangeSize. Padded ranges are well under 100KB.
+const DefaultMaxRangeSize = 4 << 20
+
+var (
+	// Gzip decodes gzip compressed responses.
+	Gzip Decoder = gzipDecoder{}
+
+	// ErrResponseTooLarge is returned while reading a range response whose
+	// decompressed size exceeds the limit, e.g. a decompression bomb.
+	ErrResponseTooLarge = errors.New("response body exceeds size limit")
+)
+
+func (gzipDecoder) Encoding() string { return "gzip" }
+
+func (gzipDecoder) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+func (c *Client) decoders() []Decoder {
+	if len(c.Decoders) == 0 {
+		return []Decoder{Gzip}
+	}
+	return c.Decoders
+}
+
+// acceptEncoding lists the encodings of the client's decoders, in order of
+// preference.
+func (c *Client) acceptEncoding() string {
+	decoders := c.decoders()
+	encodings := make([]string, len(decoders))
+	for i, d := range decoders {
+		encodings[i] = d.Encoding()
+	}
+	return strings.Join(encodings, ", ")
+}
+
+// decodeBody replaces the body of resp with its decompressed content,
+// limited to the client's MaxRangeSize.
+func (c *Client) decodeBody(resp *http.Response) error {
+	body := resp.Body
+	if encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))); encoding != "" && encoding != "identity" {
+		var decoder Decoder
+		for _, d := range c.decoders() {
+			if strings.EqualFold(d.Encoding(), encoding) {
+				decoder = d
+			}
+		}
+		if decoder == nil {
+			closeBody(resp.Body)
+			return fmt.Errorf("unsupported content encoding %q", encoding)
+		}
+
+		r, err := decoder.NewReader(resp.Body)
+		if err != nil {
+			closeBody(resp.Body)
+			return err
+		}
+		body = &decodedBody{ReadCloser: r, raw: resp.Body}
+
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+		resp.Uncompressed = true
+	}
+
+	limit := c.MaxRangeSize
+	if limit <= 0 {
+		limit = DefaultMaxRangeSize
+	}
+	resp.Body = &limitedBody{ReadCloser: body, left: limit}
+	return nil
+}
+
+func (b *decodedBody) Close() error {
+	b.ReadCloser.Close()
+	return closeBody(b.raw)
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.left < 0 {
+		return 0, ErrResponseTooLarge
+	}
+	if int64(len(p)) > b.left+1 {
+		p = p[:b.left+1]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.left {
+		n, b.left = int(b.left), -1
+		return n, ErrResponseTooLarge
+	}
+	b.left -= int64(n)
+	return n, err
+}
@@ -0,0 +1,222 @@
+package gopwned
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rangeBody is a padded range response of 800 lines.
+var rangeBody = func() []byte {
+	var b bytes.Buffer
+	for i := 0; i < 800; i++ {
+		fmt.Fprintf(&b, "%035X:%d\r\n", sha1.Sum([]byte{byte(i), byte(i >> 8)}), i%3)
+	}
+	return b.Bytes()
+}()
+
+// newGzipServer serves body gzip compressed to requests accepting it.
+func newGzipServer(t testing.TB, body []byte) (*httptest.Server, *Client) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write(body)
+	zw.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(compressed.Bytes())
+			return
+		}
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(nil, "")
+	client.PwnPwdURL, _ = url.Parse(server.URL + "/range/")
+	return server, client
+}
+
+func TestGzipRange(t *testing.T) {
+	assert := assert.New(t)
+	_, client := newGzipServer(t, rangeBody)
+
+	var meta Response
+	got, err := client.WithResponse(&meta).GetPwnedPasswords("21BD1", true)
+	if assert.NoError(err, "[TestGzipRange] returned error") {
+		assert.Equal(rangeBody, got, "[TestGzipRange] Expected the decompressed range.")
+	}
+	assert.Empty(meta.Header.Get("Content-Encoding"), "[TestGzipRange] Expected Content-Encoding to be removed once decoded.")
+}
+
+// upperDecoder stands in for an encoding such as brotli.
+type upperDecoder struct{}
+
+func (upperDecoder) Encoding() string { return "x-upper" }
+
+func (upperDecoder) NewReader(r io.Reader) (io.ReadCloser, error) {
+	data, err := ioutil.ReadAll(r)
+	return ioutil.NopCloser(bytes.NewReader(bytes.ToUpper(data))), err
+}
+
+func TestCustomDecoder(t *testing.T) {
+	assert := assert.New(t)
+
+	var accepted string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accepted = r.Header.Get("Accept-Encoding")
+		switch r.URL.Path {
+		case "/range/21BD1":
+			w.Header().Set("Content-Encoding", "x-upper")
+		case "/range/21BD2":
+			w.Header().Set("Content-Encoding", "deflate")
+		}
+		w.Write([]byte("0018a45c4d1def81644b54ab7f969b88d65:1"))
+	}))
+	defer server.Close()
+
+	client := NewClient(nil, "")
+	client.PwnPwdURL, _ = url.Parse(server.URL + "/range/")
+	client.Decoders = []Decoder{upperDecoder{}, Gzip}
+
+	got, err := client.GetPwnedPasswords("21BD1", false)
+	assert.NoError(err, "[TestCustomDecoder] returned error")
+	assert.Equal("0018A45C4D1DEF81644B54AB7F969B88D65:1", string(got), "[TestCustomDecoder] Expected the custom decoder to be used.")
+	assert.Equal("x-upper, gzip", accepted, "[TestCustomDecoder] Expected every decoder to be accepted, in order.")
+
+	_, err = client.GetPwnedPasswords("21BD2", false)
+	assert.Error(err, "[TestCustomDecoder] Expected an error for an unsupported encoding.")
+}
+
+func TestDecompressionBomb(t *testing.T) {
+	assert := assert.New(t)
+	_, client := newGzipServer(t, make([]byte, DefaultMaxRangeSize+1))
+
+	_, err := client.GetPwnedPasswords("21BD1", false)
+	assert.Equal(ErrResponseTooLarge, err, "[TestDecompressionBomb] Expected the default limit to apply.")
+
+	client.MaxRangeSize = int64(len(rangeBody)) - 1
+	_, small := newGzipServer(t, rangeBody)
+	client.PwnPwdURL = small.PwnPwdURL
+	_, err = client.GetPwnedPasswords("21BD1", false)
+	assert.Equal(ErrResponseTooLarge, err, "[TestDecompressionBomb] Expected MaxRangeSize to apply.")
+
+	client.MaxRangeSize = int64(len(rangeBody))
+	got, err := client.GetPwnedPasswords("21BD1", false)
+	assert.NoError(err, "[TestDecompressionBomb] Expected a range of exactly MaxRangeSize to be read.")
+	assert.Equal(rangeBody, got)
+}
+
+// countingBody counts the bytes read off the wire.
+type countingBody struct {
+	io.ReadCloser
+	n *int64
+}
+
+func (b countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	atomic.AddInt64(b.n, int64(n))
+	return n, err
+}
+
+func BenchmarkRangeBytesGzip(b *testing.B) {
+	_, client := newGzipServer(b, rangeBody)
+	benchmarkRangeBytes(b, client)
+}
+
+func BenchmarkRangeBytesIdentity(b *testing.B) {
+	_, client := newGzipServer(b, rangeBody)
+	client.Use(Header("Accept-Encoding", "identity"))
+	benchmarkRangeBytes(b, client)
+}
+
+func BenchmarkRangeBatchGzip(b *testing.B) {
+	_, client := newGzipServer(b, rangeBody)
+	benchmarkRangeBatch(b, client)
+}
+
+func BenchmarkRangeBatchIdentity(b *testing.B) {
+	_, client := newGzipServer(b, rangeBody)
+	client.Use(Header("Accept-Encoding", "identity"))
+	benchmarkRangeBatch(b, client)
+}
+
+// countWire counts the body bytes the client reads off the wire.
+func countWire(client *Client) *int64 {
+	var wire int64
+	client.Use(func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.Do(req)
+			if err == nil {
+				resp.Body = countingBody{ReadCloser: resp.Body, n: &wire}
+			}
+			return resp, err
+		})
+	})
+	return &wire
+}
+
+// benchmarkRangeBytes reports the body bytes transferred per padded lookup,
+// and the decoded bytes through SetBytes.
+func benchmarkRangeBytes(b *testing.B, client *Client) {
+	wire := countWire(client)
+
+	b.SetBytes(int64(len(rangeBody)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.GetPwnedPasswords("21BD1", true); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(atomic.LoadInt64(wire))/float64(b.N), "wire-B/op")
+}
+
+// rangeBatch - the number of prefixes looked up per batch.
+const rangeBatch = 64
+
+// benchmarkRangeBatch looks up a batch of distinct prefixes per op over 8
+// concurrent workers, the way a bulk password audit would, and reports the
+// body bytes transferred per batch.
+func benchmarkRangeBatch(b *testing.B, client *Client) {
+	wire := countWire(client)
+	prefixes := make([]string, rangeBatch)
+	for i := range prefixes {
+		prefixes[i] = fmt.Sprintf("%05X", i*4099)
+	}
+
+	b.SetBytes(int64(len(rangeBody)) * rangeBatch)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		jobs := make(chan string)
+		errs := make(chan error, len(prefixes))
+		for w := 0; w < 8; w++ {
+			go func() {
+				for prefix := range jobs {
+					_, err := client.GetPwnedPasswords(prefix, true)
+					errs <- err
+				}
+			}()
+		}
+		for _, prefix := range prefixes {
+			jobs <- prefix
+		}
+		close(jobs)
+		for range prefixes {
+			if err := <-errs; err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.ReportMetric(float64(atomic.LoadInt64(wire))/float64(b.N), "wire-B/op")
+}
@@ -3,6 +3,7 @@
 package gopwned
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,6 +12,9 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/mavjs/goPwned/account"
 )
 
 type (
@@ -21,6 +25,46 @@ type (
 		UserAgent string
 		BaseURL   *url.URL
 		PwnPwdURL *url.URL
+
+		// Cache, if set, stores responses of the breach catalogue and data
+		// classes endpoints for the duration given by CacheTTL. Per-account
+		// results are only cached when CacheAccounts is true.
+		Cache         Cache
+		CacheTTL      map[string]time.Duration
+		CacheAccounts bool
+
+		// Keys, if set, is used instead of Token: authenticated requests
+		// are spread over the keys of the pool.
+		Keys *KeyPool
+
+		// Limiter, if set, paces the requests that require an API key so
+		// they stay within the key's rate limit.
+		Limiter *Limiter
+
+		// Logger, Metrics and Tracer, if set, instrument every API call.
+		// The API key is never logged.
+		Logger  Logger
+		Metrics Metrics
+		Tracer  Tracer
+
+		// RangeCache, if set, serves range lookups from disk.
+		RangeCache *RangeCache
+
+		// Breaker, if set, fails calls fast while the API keeps failing.
+		Breaker *Breaker
+
+		// Hedger, if set, duplicates range requests that are slow to
+		// answer, to cut tail latency.
+		Hedger *Hedger
+
+		// Decoders decompress range responses, in order of preference.
+		// Gzip is used if it is empty. MaxRangeSize limits the decompressed
+		// size of a range response, DefaultMaxRangeSize if it is 0.
+		Decoders     []Decoder
+		MaxRangeSize int64
+
+		middleware []Middleware
+		response   *Response
 	}
 
 	// Breach holds all breach information returned from the API.
@@ -55,6 +99,10 @@ type (
 	// DataClasses holds all data classes exposed from breaches returned
 	// from the API.
 	DataClasses []string
+
+	// DomainBreaches maps each breached alias of a domain, i.e. the part
+	// before the "@", to the names of the breaches it appeared in.
+	DomainBreaches map[string][]string
 )
 
 const (
@@ -74,6 +122,10 @@ const (
 )
 
 var (
+	// ErrNotFound is returned when the API responds with 404, e.g. for an
+	// account that has not been pwned.
+	ErrNotFound = errors.New(respCodes[404])
+
 	// respCodes - a list of response codes and their expected values as
 	// defined by HIBP API: https://haveibeenpwned.com/API/v3#ResponseCodes
 	respCodes = map[int]string{
@@ -91,13 +143,14 @@ var (
 // 1) a `http.Client`
 // 2) an API key
 //
-// Currently, the 1st argument will default to `http.DefaultClient` if no
-// arguments are given. The 2nd argument will default to an empty string, which
-// means the client will not be able to call certain endpoints as per the API
-// version changes in V3. For more information: https://haveibeenpwned.com/API/v3
+// Currently, the 1st argument will default to a client using `NewTransport`,
+// which keeps connections alive, if no arguments are given. The 2nd argument
+// will default to an empty string, which means the client will not be able to
+// call certain endpoints as per the API version changes in V3. For more
+// information: https://haveibeenpwned.com/API/v3
 func NewClient(httpClient *http.Client, token string) *Client {
 	if httpClient == nil {
-		httpClient = &http.Client{}
+		httpClient = &http.Client{Transport: NewTransport()}
 	}
 
 	baseURL, _ := url.Parse(endpoint)
@@ -113,9 +166,26 @@ func checkAPI(path string) bool {
 		return false
 	case strings.Contains(path, "/pasteaccount/") || strings.Contains(path, "/breachedaccount/"):
 		return true
+	case strings.Contains(path, "/breacheddomain/"):
+		return true
+	}
+}
+
+// statusError returns the error for a non-200 response status code.
+func statusError(code int) error {
+	if code == http.StatusNotFound {
+		return ErrNotFound
 	}
+	return errors.New(respCodes[code])
 }
-func (c *Client) newRequest(resource string, opts url.Values) (*http.Response, error) {
+
+func (c *Client) newRequest(resource string, opts url.Values) (_ *http.Response, err error) {
+	group := endpointGroup(resource)
+	call := c.begin(group)
+
+	var req *http.Request
+	defer func() { call.end(req, err) }()
+
 	target, err := c.BaseURL.Parse(resource)
 	if err != nil {
 		return nil, err
@@ -125,44 +195,101 @@ func (c *Client) newRequest(resource string, opts url.Values) (*http.Response, e
 		target.RawQuery = opts.Encode()
 	}
 
-	req, err := http.NewRequest("GET", target.String(), nil)
+	req, err = http.NewRequestWithContext(call.ctx, "GET", target.String(), nil)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("User-Agent", c.UserAgent)
-	if checkAPI(target.String()) {
-		if c.Token != "" {
-			req.Header.Set("hibp-api-key", c.Token)
-		} else {
+	// With a key pool, doPooled sets the key of every attempt instead.
+	authenticated := checkAPI(target.String())
+	if authenticated && c.Keys == nil {
+		if c.Token == "" {
 			return nil, errors.New("the function you're trying to request requires an API key")
 		}
+		req.Header.Set("hibp-api-key", c.Token)
+	}
+	key := target.String()
+	useCache := c.cacheable(group, key)
+
+	var cached *CacheEntry
+	if useCache {
+		if entry, ok := c.Cache.Get(key); ok {
+			if time.Now().Before(entry.Expires) {
+				call.hit(entry)
+				return cachedResponse(req, entry), nil
+			}
+			cached = entry
+			if entry.ETag != "" {
+				req.Header.Set("If-None-Match", entry.ETag)
+			}
+			if entry.LastModified != "" {
+				req.Header.Set("If-Modified-Since", entry.LastModified)
+			}
+		}
+	}
+
+	// Only requests that reach the API count against its rate limit.
+	if authenticated && c.Limiter != nil {
+		if err := c.Limiter.Wait(call.ctx); err != nil {
+			return nil, err
+		}
 	}
-	req.Close = true
 
 	// Note: An error is returned if caused by client policy (such as CheckRedirect),
 	// or failure to speak HTTP (such as a network connectivity problem).
 	// A non-2xx status code doesn't cause an error.
-	resp, err := c.client.Do(req)
+	if err := c.Breaker.allow(call, "api"); err != nil {
+		return nil, err
+	}
+	var resp *http.Response
+	if authenticated && c.Keys != nil {
+		resp, err = c.doPooled(req)
+	} else {
+		resp, err = c.do(req)
+	}
+	call.response(resp, err)
+	c.Breaker.record(call, "api", resp, err)
 	if err != nil {
 		return nil, err
 	}
 
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		closeBody(resp.Body)
+		revalidated := *cached
+		revalidated.Expires = time.Now().Add(c.cacheTTL(group))
+		call.hit(&revalidated)
+		c.Cache.Set(key, &revalidated)
+		return cachedResponse(req, &revalidated), nil
+	}
+
 	if resp.StatusCode != 200 {
-		return nil, errors.New(respCodes[resp.StatusCode])
+		closeBody(resp.Body)
+		return nil, statusError(resp.StatusCode)
+	}
+
+	if useCache {
+		if err := c.storeResponse(key, group, resp); err != nil {
+			return nil, err
+		}
 	}
 
 	return resp, nil
 }
 
-func (c *Client) newPwdRequest(resource string, addPadding bool) (*http.Response, error) {
+func (c *Client) newPwdRequest(resource string, addPadding bool) (_ *http.Response, err error) {
+	call := c.begin("range")
+
+	var req *http.Request
+	defer func() { call.end(req, err) }()
+
 	target, err := c.PwnPwdURL.Parse(resource)
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := http.NewRequest("GET", target.String(), nil)
+	req, err = http.NewRequestWithContext(call.ctx, "GET", target.String(), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -171,21 +298,52 @@ func (c *Client) newPwdRequest(resource string, addPadding bool) (*http.Response
 		req.Header.Set("Add-Padding", strconv.FormatBool(addPadding))
 	}
 	req.Header.Set("User-Agent", c.UserAgent)
-
-	req.Close = true
+	req.Header.Set("Accept-Encoding", c.acceptEncoding())
+
+	prefix, mode := rangeKey(target)
+	if c.RangeCache != nil {
+		if body, ok := c.RangeCache.Get(prefix, mode); ok {
+			entry := &CacheEntry{Body: body}
+			call.hit(entry)
+			return cachedResponse(req, entry), nil
+		}
+	}
 
 	// Note: An error is returned if caused by client policy (such as CheckRedirect),
 	// or failure to speak HTTP (such as a network connectivity problem).
 	// A non-2xx status code doesn't cause an error.
-	resp, err := c.client.Do(req)
+	if err := c.Breaker.allow(call, "range"); err != nil {
+		return nil, err
+	}
+	resp, err := c.doHedged(req)
+	call.response(resp, err)
+	c.Breaker.record(call, "range", resp, err)
 	if err != nil {
 		return nil, err
 	}
 
 	if resp.StatusCode != 200 {
-		return nil, errors.New(respCodes[resp.StatusCode])
+		closeBody(resp.Body)
+		return nil, statusError(resp.StatusCode)
+	}
+
+	if err := c.decodeBody(resp); err != nil {
+		return nil, err
 	}
 
+	if c.RangeCache != nil {
+		body, err := ioutil.ReadAll(resp.Body)
+		closeBody(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		// The lookup succeeded, a cache that cannot be written only costs
+		// the next lookup a request.
+		if err := c.RangeCache.Set(prefix, mode, body); err != nil {
+			call.cacheFailed(err)
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
 	return resp, nil
 }
 
@@ -194,7 +352,7 @@ func (c *Client) getBreaches(resource string, opts url.Values) ([]*Breach, error
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	defer closeBody(resp.Body)
 
 	var breaches []*Breach
 	err = json.NewDecoder(resp.Body).Decode(&breaches)
@@ -205,13 +363,17 @@ func (c *Client) getBreaches(resource string, opts url.Values) ([]*Breach, error
 // been involved in. This function checks if an HIBP API key is provided, if not
 // it will throw an error.
 // The function accepts 4 arguments, with 1 of them being required. They are:
-//     - account - The account is not case sensitive and is URL encoded before sending to the endpoint. (required)
+//     - acct - The email address or username. It is not case sensitive, and is validated and normalized by package account before sending to the endpoint. (required)
 //     - domain - Filters the result set to only breaches against the domain specified. (e.g. adobe.com)
 //     - truncate - Instructs the API to return the full breach data instead of, by default, only the name of the breach.
 //     - unverified - Instructs the API not to include unverified breaches instead of, by default, returning both verified and unverified.
-func (c *Client) GetAccountBreaches(account, domain string, truncate, unverified bool) ([]*Breach, error) {
+func (c *Client) GetAccountBreaches(acct, domain string, truncate, unverified bool) ([]*Breach, error) {
+	normalized, err := account.Normalize(acct)
+	if err != nil {
+		return nil, err
+	}
 
-	resource := fmt.Sprintf("breachedaccount/%s", url.QueryEscape(account))
+	resource := fmt.Sprintf("breachedaccount/%s", account.PathSegment(normalized))
 
 	opts := url.Values{}
 	if domain != "" {
@@ -243,23 +405,67 @@ func (c *Client) GetBreachedSites(domainFilter string) ([]*Breach, error) {
 // This breach "name" is a stable value in the haveibeenpwned.com data-sets.
 // An example of a breach "name" would be "Adobe" instead of "adobe.com".
 func (c *Client) GetABreachedSite(site string) (*Breach, error) {
+	site = strings.TrimSpace(site)
 	if site == "" {
 		return nil, errors.New("a breach name was not provided")
 	}
 
-	resource := fmt.Sprintf("breach/%s", site)
+	resource := fmt.Sprintf("breach/%s", account.PathSegment(site))
 
 	resp, err := c.newRequest(resource, nil)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	defer closeBody(resp.Body)
 
 	var breaches *Breach
 	err = json.NewDecoder(resp.Body).Decode(&breaches)
 	return breaches, err
 }
 
+// GetLatestBreach - returns the most recently added breach, based on its
+// "AddedDate". It is a cheap way to poll for new breaches without downloading
+// the whole catalogue.
+func (c *Client) GetLatestBreach() (*Breach, error) {
+	resp, err := c.newRequest("latestbreach", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp.Body)
+
+	var breach *Breach
+	err = json.NewDecoder(resp.Body).Decode(&breach)
+	return breach, err
+}
+
+// GetDomainBreaches - returns every breached alias of a domain along with the
+// names of the breaches it appeared in. The domain has to be verified on the
+// HIBP dashboard for the API key in use, so this function checks if an HIBP
+// API key is provided, if not it will throw an error. Internationalized domains
+// are converted to their ASCII form.
+func (c *Client) GetDomainBreaches(domain string) (DomainBreaches, error) {
+	if domain == "" {
+		return nil, errors.New("a domain was not provided")
+	}
+
+	normalized, err := account.Domain(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	resource := fmt.Sprintf("breacheddomain/%s", account.PathSegment(normalized))
+
+	resp, err := c.newRequest(resource, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp.Body)
+
+	var aliases DomainBreaches
+	err = json.NewDecoder(resp.Body).Decode(&aliases)
+	return aliases, err
+}
+
 // GetDataClasses - returns an alphabetically ordered list of data classes exposed
 // during a breach. A "data class" is an attribute of a record compromised in a
 // breach. E.g. "Email addresses" and "Passwords"
@@ -270,7 +476,7 @@ func (c *Client) GetDataClasses() (*DataClasses, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	defer closeBody(resp.Body)
 
 	var dataclasses *DataClasses
 	err = json.NewDecoder(resp.Body).Decode(&dataclasses)
@@ -279,17 +485,21 @@ func (c *Client) GetDataClasses() (*DataClasses, error) {
 
 // GetAccountPastes - returns a list of pastes based on the email provided.
 // This function checks if an HIBP API key is provided, if not it will throw an
-// error.
-//
+// error. The email address is validated and normalized locally, so a malformed
+// one never reaches the API.
 func (c *Client) GetAccountPastes(email string) ([]*Paste, error) {
+	normalized, err := account.Email(email)
+	if err != nil {
+		return nil, err
+	}
 
-	resource := fmt.Sprintf("pasteaccount/%s", email)
+	resource := fmt.Sprintf("pasteaccount/%s", account.PathSegment(normalized))
 
 	resp, err := c.newRequest(resource, nil)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	defer closeBody(resp.Body)
 
 	var pastes []*Paste
 	err = json.NewDecoder(resp.Body).Decode(&pastes)
@@ -306,7 +516,7 @@ func (c *Client) GetPwnedPasswords(chars string, addPadding bool) ([]byte, error
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	defer closeBody(resp.Body)
 
 	respBody, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
@@ -4,16 +4,21 @@ import (
 	"crypto/sha1"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/mavjs/goPwned/account"
+	"github.com/mavjs/goPwned/replay"
 )
 
 var (
@@ -24,6 +29,8 @@ var (
 
 	mockHandler *http.ServeMux
 	mockServer  *httptest.Server
+
+	record = flag.Bool("record", false, "record the live API tests into testdata/replay instead of replaying them")
 )
 
 func init() {
@@ -46,6 +53,43 @@ func checkHeader(t *testing.T) http.HandlerFunc {
 	}
 }
 
+// replayClient returns a client whose requests are answered from the cassette
+// of the test in testdata/replay. The committed cassettes are synthetic
+// fixtures, not recordings; with -record the requests go to the live API and
+// the cassette is written again.
+func replayClient(t *testing.T, token string) *Client {
+	mode := replay.Replaying
+	if *record {
+		mode = replay.Recording
+	}
+
+	rec, err := replay.New(filepath.Join("testdata", "replay", t.Name()+".json"), mode)
+	if err != nil {
+		t.Fatalf("[%s] %v", t.Name(), err)
+	}
+	t.Cleanup(func() {
+		if err := rec.Save(); err != nil {
+			t.Errorf("[%s] unable to save the recording: %v", t.Name(), err)
+		}
+	})
+
+	return NewClient(&http.Client{Transport: rec}, token)
+}
+
+// apiKey returns the HIBP API key to record with. Recorded keys are scrubbed,
+// so a placeholder is enough when replaying.
+func apiKey(t *testing.T) string {
+	if !*record {
+		return "replayed-api-key"
+	}
+
+	key := os.Getenv("HIBP_API_KEY")
+	if key == "" {
+		t.Skipf("[%s] Skipped recording as API key was not provided.", t.Name())
+	}
+	return key
+}
+
 func setupPasswordInput() (string, string) {
 	inputPassword := "P@ssw0rd"
 	h := sha1.New()
@@ -104,7 +148,7 @@ func TestWrongAPIKey(t *testing.T) {
 
 	want_error := errors.New(respCodes[401])
 
-	gopwn := NewClient(nil, "InvalidAPIKey")
+	gopwn := replayClient(t, "InvalidAPIKey")
 	_, got_err := gopwn.GetAccountBreaches("account-exists@hibp-integration-tests.com", "", true, false)
 	if got_err != nil {
 		assert.Equal(want_error, got_err, "[TestWrongAPIKey] Expected to return a message based on HTTP Status Code 401.")
@@ -112,11 +156,6 @@ func TestWrongAPIKey(t *testing.T) {
 }
 
 func TestAccountExists(t *testing.T) {
-	HIBP_API_KEY := os.Getenv("HIBP_API_KEY")
-	if HIBP_API_KEY == "" {
-		t.Skip("[TestAccountExists] Skipped test as API key was not provided.")
-	}
-
 	assert := assert.New(t)
 	want := []*Breach{
 		{
@@ -138,7 +177,7 @@ func TestAccountExists(t *testing.T) {
 		},
 	}
 
-	gopwn := NewClient(nil, HIBP_API_KEY)
+	gopwn := replayClient(t, apiKey(t))
 	got, err := gopwn.GetAccountBreaches("account-exists@hibp-integration-tests.com", "", true, false)
 	if err != nil {
 		t.Errorf("[TestWithAPICheckAccountExists] Returned errors: %v", err)
@@ -147,11 +186,6 @@ func TestAccountExists(t *testing.T) {
 }
 
 func TestAccountDomain(t *testing.T) {
-	HIBP_API_KEY := os.Getenv("HIBP_API_KEY")
-	if HIBP_API_KEY == "" {
-		t.Skip("[TestAccountDomain] Skipped test as API key was not provided.")
-	}
-
 	assert := assert.New(t)
 	want := []*Breach{
 		{
@@ -173,7 +207,7 @@ func TestAccountDomain(t *testing.T) {
 		},
 	}
 
-	gopwn := NewClient(nil, HIBP_API_KEY)
+	gopwn := replayClient(t, apiKey(t))
 	got, err := gopwn.GetAccountBreaches("account-exists@hibp-integration-tests.com", "adobe.com", true, false)
 	if err != nil {
 		t.Errorf("[TestAccountDomain] Returned errors: %v", err)
@@ -182,14 +216,9 @@ func TestAccountDomain(t *testing.T) {
 }
 
 func TestNotActiveBreach(t *testing.T) {
-	HIBP_API_KEY := os.Getenv("HIBP_API_KEY")
-	if HIBP_API_KEY == "" {
-		t.Skip("[TestNotActiveBreach] Skipped test as API key was not provided.")
-	}
-
 	assert := assert.New(t)
 
-	gopwn := NewClient(nil, HIBP_API_KEY)
+	gopwn := replayClient(t, apiKey(t))
 
 	got, err := gopwn.GetAccountBreaches("not-active-breach@hibp-integration-tests.com", "", true, false)
 	if err != nil {
@@ -202,11 +231,6 @@ func TestNotActiveBreach(t *testing.T) {
 }
 
 func TestPasteBreach(t *testing.T) {
-	HIBP_API_KEY := os.Getenv("HIBP_API_KEY")
-	if HIBP_API_KEY == "" {
-		t.Skip("[TestPasteBreach] Skipping test as API key was not provided.")
-	}
-
 	assert := assert.New(t)
 
 	want := []*Paste{
@@ -218,7 +242,7 @@ func TestPasteBreach(t *testing.T) {
 			EmailCount: 1117,
 		},
 	}
-	gopwn := NewClient(nil, HIBP_API_KEY)
+	gopwn := replayClient(t, apiKey(t))
 
 	got, err := gopwn.GetAccountPastes("paste-sensitive-breach@hibp-integration-tests.com")
 	if err != nil {
@@ -233,7 +257,7 @@ func TestPasswordBreach(t *testing.T) {
 
 	want := int64(83129)
 
-	gopwned := NewClient(nil, "")
+	gopwned := replayClient(t, "")
 
 	frange, lrange := setupPasswordInput()
 
@@ -255,7 +279,7 @@ func TestPasswordBreachWithPadding(t *testing.T) {
 
 	want := int64(83129)
 
-	gopwned := NewClient(nil, "")
+	gopwned := replayClient(t, "")
 
 	frange, lrange := setupPasswordInput()
 
@@ -275,7 +299,7 @@ func TestPasswordBreachWithPadding(t *testing.T) {
 func TestPasswordBreachWrongChars(t *testing.T) {
 	assert := assert.New(t)
 
-	gopwned := NewClient(nil, "")
+	gopwned := replayClient(t, "")
 
 	karray, err := gopwned.GetPwnedPasswords("1234G", true)
 	if err != nil {
@@ -552,7 +576,7 @@ func TestGetBreachedSitesFiltered(t *testing.T) {
 		},
 	}
 
-	gopwned := NewClient(nil, "")
+	gopwned := replayClient(t, "")
 
 	got, err := gopwned.GetBreachedSites("adobe.com")
 	if err != nil {
@@ -561,3 +585,107 @@ func TestGetBreachedSitesFiltered(t *testing.T) {
 
 	assert.Equal(want, got, "[TestGetBreachedSitesFiltered] Expected equal value for breached sites.")
 }
+
+func TestGetLatestBreach(t *testing.T) {
+	assert := assert.New(t)
+
+	mockHandler.HandleFunc("/latestbreach", func(w http.ResponseWriter, r *http.Request) {
+		checkHeader(t)(w, r)
+		fmt.Fprint(w, `{"Name": "Canva", "AddedDate": "2019-08-09T14:24:01Z"}`)
+	})
+
+	gopwned := NewClient(nil, "")
+	gopwned.BaseURL, _ = url.Parse(mockServer.URL)
+
+	got, err := gopwned.GetLatestBreach()
+	if err != nil {
+		t.Fatalf("[TestGetLatestBreach] returned error: %v", err)
+	}
+
+	want := &Breach{
+		Name:      "Canva",
+		AddedDate: "2019-08-09T14:24:01Z",
+	}
+	assert.Equal(want, got, "[TestGetLatestBreach] Expected equal value for the latest breach.")
+}
+
+func TestGetDomainBreaches(t *testing.T) {
+	assert := assert.New(t)
+
+	mockHandler.HandleFunc("/breacheddomain/example.com", func(w http.ResponseWriter, r *http.Request) {
+		checkHeader(t)(w, r)
+		if r.Header.Get("hibp-api-key") != "APIKEY" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"alice":["Adobe"],"bob":["Adobe","Canva"]}`)
+	})
+
+	gopwned := NewClient(nil, "")
+	gopwned.BaseURL, _ = url.Parse(mockServer.URL)
+
+	_, err := gopwned.GetDomainBreaches("example.com")
+	assert.EqualError(err, "the function you're trying to request requires an API key")
+
+	gopwned.Token = "APIKEY"
+	got, err := gopwned.GetDomainBreaches("example.com")
+	if err != nil {
+		t.Fatalf("[TestGetDomainBreaches] returned error: %v", err)
+	}
+
+	want := DomainBreaches{
+		"alice": {"Adobe"},
+		"bob":   {"Adobe", "Canva"},
+	}
+	assert.Equal(want, got, "[TestGetDomainBreaches] Expected equal value for domain breaches.")
+}
+
+func TestNotFound(t *testing.T) {
+	assert := assert.New(t)
+
+	mockHandler.HandleFunc("/breach/Unknown", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	gopwned := NewClient(nil, "")
+	gopwned.BaseURL, _ = url.Parse(mockServer.URL)
+
+	_, err := gopwned.GetABreachedSite("Unknown")
+	assert.Equal(ErrNotFound, err, "[TestNotFound] Expected ErrNotFound for HTTP Status Code 404.")
+	assert.EqualError(err, respCodes[404])
+}
+
+func TestAccountEscaping(t *testing.T) {
+	assert := assert.New(t)
+
+	var paths []string
+	record := func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath())
+		fmt.Fprint(w, `[]`)
+	}
+	mockHandler.HandleFunc("/breachedaccount/", record)
+	mockHandler.HandleFunc("/pasteaccount/", record)
+
+	gopwned := NewClient(nil, "APIKEY")
+	gopwned.BaseURL, _ = url.Parse(mockServer.URL)
+
+	_, err := gopwned.GetAccountBreaches(" Odd/Path?@Example.com", "", true, false)
+	assert.NoError(err)
+	_, err = gopwned.GetAccountPastes("user@Bücher.example")
+	assert.NoError(err)
+	_, err = gopwned.GetAccountBreaches("Foo Bar", "", true, false)
+	assert.NoError(err)
+	assert.Equal([]string{
+		"/breachedaccount/odd%2Fpath%3F@example.com",
+		"/pasteaccount/user@xn--bcher-kva.example",
+		"/breachedaccount/foo%20bar",
+	}, paths, "[TestAccountEscaping] Expected normalized accounts escaped as a single path segment, with spaces as %20.")
+
+	for _, input := range []string{"", "foo\tbar", "user@localhost"} {
+		_, err = gopwned.GetAccountBreaches(input, "", true, false)
+		assert.True(errors.Is(err, account.ErrInvalid), "[TestAccountEscaping] Expected %q to be rejected. Got: %v", input, err)
+	}
+	_, err = gopwned.GetAccountPastes("troyhunt")
+	assert.True(errors.Is(err, account.ErrInvalid), "[TestAccountEscaping] Expected pastes to require an email address.")
+	assert.Len(paths, 3, "[TestAccountEscaping] Expected malformed accounts not to reach the API.")
+}
@@ -0,0 +1,231 @@
+package gopwned
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+type (
+	// Clock tells the time and runs timers, so tests can control time.
+	Clock interface {
+		Now() time.Time
+		After(d time.Duration) <-chan time.Time
+	}
+
+	systemClock struct{}
+
+	// Hedger sends a duplicate range request when the first one has not
+	// answered within the Percentile of recent response times, and uses
+	// whichever response arrives first. Until enough response times are
+	// known, and as a lower bound, MinDelay is used. Budget caps the share
+	// of requests that may be duplicated, e.g. 0.05 for 5% extra load: every
+	// request adds Budget to a token bucket holding at most the duplicates
+	// allowed over the last 100 requests, and every duplicate takes one
+	// token, so a calm stretch cannot be saved up for a later slowdown.
+	// A Hedger is safe for concurrent use.
+	Hedger struct {
+		Percentile float64
+		MinDelay   time.Duration
+		Budget     float64
+		Clock      Clock
+
+		mu      sync.Mutex
+		samples []time.Duration
+		next    int
+		tokens  float64
+	}
+
+	// attempt is the outcome of one of the requests sent for a call.
+	attempt struct {
+		resp *http.Response
+		err  error
+		i    int
+	}
+
+	// cancelBody cancels the context of its request once closed.
+	cancelBody struct {
+		io.ReadCloser
+		cancel context.CancelFunc
+	}
+)
+
+const (
+	// hedgeWindow - the number of recent response times kept.
+	hedgeWindow = 100
+
+	// hedgeMinSamples - the number of response times needed before the
+	// percentile is used instead of MinDelay.
+	hedgeMinSamples = 20
+)
+
+// NewHedger creates a hedger firing a duplicate after the percentile, e.g.
+// 0.95, of recent response times, no earlier than minDelay, for at most the
+// budget share of requests.
+func NewHedger(percentile float64, minDelay time.Duration, budget float64) *Hedger {
+	return &Hedger{Percentile: percentile, MinDelay: minDelay, Budget: budget}
+}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (h *Hedger) clock() Clock {
+	if h.Clock == nil {
+		return systemClock{}
+	}
+	return h.Clock
+}
+
+// Delay returns how long a request may take before it is duplicated.
+func (h *Hedger) Delay() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.samples) < hedgeMinSamples {
+		return h.MinDelay
+	}
+	sorted := append([]time.Duration(nil), h.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	i := int(math.Ceil(h.Percentile*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	} else if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	if sorted[i] < h.MinDelay {
+		return h.MinDelay
+	}
+	return sorted[i]
+}
+
+// observe records the response time of a request.
+func (h *Hedger) observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.samples) < hedgeWindow {
+		h.samples = append(h.samples, d)
+		return
+	}
+	h.samples[h.next] = d
+	h.next = (h.next + 1) % hedgeWindow
+}
+
+// begin adds the budget of a request that may be hedged to the bucket.
+func (h *Hedger) begin() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	burst := h.Budget * hedgeWindow
+	if burst < 1 {
+		burst = 1
+	}
+	h.tokens += h.Budget
+	if h.tokens > burst {
+		h.tokens = burst
+	}
+}
+
+// allow reports whether the bucket holds a token for another duplicate,
+// and takes it if so.
+func (h *Hedger) allow() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.tokens < 1 {
+		return false
+	}
+	h.tokens--
+	return true
+}
+
+// doHedged sends req through the middleware chain, duplicating it as the
+// client's Hedger allows. The first response wins; an attempt that fails
+// without a response only ends the call once no other one is in flight.
+func (c *Client) doHedged(req *http.Request) (*http.Response, error) {
+	h := c.Hedger
+	if h == nil {
+		return c.do(req)
+	}
+	h.begin()
+	clock := h.clock()
+
+	results := make(chan attempt, 2)
+	var cancels []context.CancelFunc
+	send := func() {
+		ctx, cancel := context.WithCancel(req.Context())
+		cancels = append(cancels, cancel)
+		i := len(cancels) - 1
+
+		go func() {
+			start := clock.Now()
+			resp, err := c.do(req.Clone(ctx))
+			if err == nil {
+				h.observe(clock.Now().Sub(start))
+			}
+			results <- attempt{resp: resp, err: err, i: i}
+		}()
+	}
+
+	send()
+	timer := clock.After(h.Delay())
+	pending := 1
+	for {
+		select {
+		case <-timer:
+			timer = nil
+			if h.allow() {
+				send()
+				pending++
+				hedged(req.Context())
+			}
+		case r := <-results:
+			pending--
+			if r.err != nil && pending > 0 {
+				cancels[r.i]()
+				continue
+			}
+			if r.err != nil {
+				cancels[r.i]()
+				return nil, r.err
+			}
+
+			for i, cancel := range cancels {
+				if i != r.i {
+					cancel()
+				}
+			}
+			go discard(results, pending)
+			r.resp.Body = &cancelBody{ReadCloser: r.resp.Body, cancel: cancels[r.i]}
+			return r.resp, nil
+		}
+	}
+}
+
+// discard closes the responses of the n attempts that lost.
+func discard(results <-chan attempt, n int) {
+	for ; n > 0; n-- {
+		if r := <-results; r.err == nil {
+			r.resp.Body.Close()
+		}
+	}
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// hedged counts a duplicate request on the call that sent req, if any.
+func hedged(ctx context.Context) {
+	if o, ok := ctx.Value(callKey{}).(*call); ok {
+		o.hedge()
+	}
+}
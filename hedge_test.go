@@ -0,0 +1,196 @@
+package gopwned
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock only moves when advanced. armed receives the delay of every
+// timer started.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []fakeTimer
+	armed  chan time.Duration
+}
+
+type fakeTimer struct {
+	at time.Time
+	c  chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), armed: make(chan time.Duration, 10)}
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	c := make(chan time.Time, 1)
+	f.timers = append(f.timers, fakeTimer{at: f.now.Add(d), c: c})
+	f.mu.Unlock()
+
+	f.armed <- d
+	return c
+}
+
+// Advance moves the clock by d and fires the timers due.
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.now = f.now.Add(d)
+	pending := f.timers[:0]
+	for _, t := range f.timers {
+		if t.at.After(f.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.c <- f.now
+	}
+	f.timers = pending
+}
+
+// newDelayServer answers range requests in order: the first one waits for
+// release to be closed, the others answer at once.
+func newDelayServer(t *testing.T) (*Client, chan struct{}, *int) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		n := requests
+		mu.Unlock()
+
+		if n == 1 {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.Write([]byte("request " + string(rune('0'+n))))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(nil, "")
+	client.PwnPwdURL, _ = url.Parse(server.URL + "/range/")
+	return client, release, &requests
+}
+
+func TestHedgeSlowRequest(t *testing.T) {
+	assert := assert.New(t)
+	client, release, requests := newDelayServer(t)
+	defer close(release)
+
+	clock := newFakeClock()
+	client.Hedger = &Hedger{Percentile: 0.95, MinDelay: 50 * time.Millisecond, Budget: 1, Clock: clock}
+	metrics := &testMetrics{counts: make(map[string]int)}
+	client.Metrics = metrics
+
+	done := make(chan []byte)
+	go func() {
+		body, err := client.GetPwnedPasswords("21BD1", false)
+		assert.NoError(err, "[TestHedgeSlowRequest] returned error")
+		done <- body
+	}()
+
+	assert.Equal(50*time.Millisecond, <-clock.armed, "[TestHedgeSlowRequest] Expected MinDelay without enough samples.")
+	clock.Advance(50 * time.Millisecond)
+
+	assert.Equal("request 2", string(<-done), "[TestHedgeSlowRequest] Expected the duplicate to win.")
+	assert.Equal(2, *requests)
+	metrics.mu.Lock()
+	assert.Equal(1, metrics.counts["hedges range 0"], "[TestHedgeSlowRequest] Expected the duplicate to be counted.")
+	metrics.mu.Unlock()
+}
+
+func TestHedgeFastRequest(t *testing.T) {
+	assert := assert.New(t)
+	client, release, requests := newDelayServer(t)
+
+	clock := newFakeClock()
+	client.Hedger = &Hedger{Percentile: 0.95, MinDelay: 50 * time.Millisecond, Budget: 1, Clock: clock}
+
+	done := make(chan []byte)
+	go func() {
+		body, _ := client.GetPwnedPasswords("21BD1", false)
+		done <- body
+	}()
+
+	<-clock.armed
+	close(release)
+	assert.Equal("request 1", string(<-done), "[TestHedgeFastRequest] Expected the first request to answer.")
+
+	clock.Advance(time.Second)
+	assert.Equal(1, *requests, "[TestHedgeFastRequest] Expected no duplicate once answered.")
+}
+
+func TestHedgeBudget(t *testing.T) {
+	assert := assert.New(t)
+	client, release, requests := newDelayServer(t)
+
+	clock := newFakeClock()
+	client.Hedger = &Hedger{Percentile: 0.95, MinDelay: 50 * time.Millisecond, Budget: 0.5, Clock: clock}
+
+	done := make(chan []byte)
+	go func() {
+		body, _ := client.GetPwnedPasswords("21BD1", false)
+		done <- body
+	}()
+
+	<-clock.armed
+	clock.Advance(time.Second)
+	close(release)
+	assert.Equal("request 1", string(<-done), "[TestHedgeBudget] Expected the budget to prevent a duplicate.")
+	assert.Equal(1, *requests)
+}
+
+func TestHedgerDelay(t *testing.T) {
+	assert := assert.New(t)
+
+	h := NewHedger(0.9, 5*time.Millisecond, 0.1)
+	for i := 1; i < hedgeMinSamples; i++ {
+		h.observe(time.Duration(i) * 10 * time.Millisecond)
+	}
+	assert.Equal(5*time.Millisecond, h.Delay(), "[TestHedgerDelay] Expected MinDelay without enough samples.")
+
+	for i := hedgeMinSamples; i <= hedgeWindow+10; i++ {
+		h.observe(time.Duration(i) * 10 * time.Millisecond)
+	}
+	assert.Equal(1000*time.Millisecond, h.Delay(), "[TestHedgerDelay] Expected the 90th percentile of the last %d samples.", hedgeWindow)
+
+	h.MinDelay = 2 * time.Second
+	assert.Equal(2*time.Second, h.Delay(), "[TestHedgerDelay] Expected MinDelay to bound the delay.")
+}
+
+func TestHedgeBudgetAfterCalm(t *testing.T) {
+	assert := assert.New(t)
+
+	h := NewHedger(0.95, time.Millisecond, 0.05)
+	for i := 0; i < 10000; i++ {
+		h.begin()
+	}
+
+	hedges := 0
+	for i := 0; i < 1000; i++ {
+		h.begin()
+		if h.allow() {
+			hedges++
+		}
+	}
+	assert.InDelta(0.05*1000, hedges, 0.05*hedgeWindow+1, "[TestHedgeBudgetAfterCalm] Expected the hedge share of a slowdown to stay near the budget after a calm stretch.")
+}
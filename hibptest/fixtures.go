@@ -0,0 +1,128 @@
+package hibptest
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Breach is a breach as served by the fake, in the JSON format of the
+	// HIBP API.
+	Breach struct {
+		Name         string   `json:"Name"`
+		Title        string   `json:"Title"`
+		Domain       string   `json:"Domain"`
+		BreachDate   string   `json:"BreachDate"`
+		AddedDate    string   `json:"AddedDate"`
+		ModifiedDate string   `json:"ModifiedDate"`
+		PwnCount     int      `json:"PwnCount"`
+		Description  string   `json:"Description"`
+		DataClasses  []string `json:"DataClasses"`
+		IsVerified   bool     `json:"IsVerified"`
+		IsFabricated bool     `json:"IsFabricated"`
+		IsSensitive  bool     `json:"IsSensitive"`
+		IsRetired    bool     `json:"IsRetired"`
+		IsSpamList   bool     `json:"IsSpamList"`
+		IsMalware    bool     `json:"IsMalware"`
+		LogoPath     string   `json:"LogoPath"`
+	}
+
+	// Paste is a paste as served by the fake.
+	Paste struct {
+		Source     string `json:"Source"`
+		ID         string `json:"Id"`
+		Title      string `json:"Title"`
+		Date       string `json:"Date"`
+		EmailCount int    `json:"EmailCount"`
+	}
+
+	// Account holds what the fake knows about an account. Breaches are
+	// referenced by name; names missing from the catalogue stand for
+	// inactive breaches and are never returned.
+	Account struct {
+		Breaches []string
+		Pastes   []*Paste
+	}
+)
+
+//go:embed fixtures
+var fixtures embed.FS
+
+// loadFixtures seeds s with the breaches, accounts and password hashes
+// modelled on the HIBP integration-test accounts.
+func (s *Server) loadFixtures() error {
+	if err := readJSON("fixtures/breaches.json", &s.Breaches); err != nil {
+		return err
+	}
+	if err := readJSON("fixtures/accounts.json", &s.Accounts); err != nil {
+		return err
+	}
+
+	var err error
+	if s.SHA1, err = readHashes("fixtures/passwords.txt"); err != nil {
+		return err
+	}
+	s.NTLM, err = readHashes("fixtures/ntlm.txt")
+	return err
+}
+
+func readJSON(name string, v interface{}) error {
+	data, err := fixtures.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("hibptest: %s: %w", name, err)
+	}
+	return nil
+}
+
+// readHashes reads "HASH:COUNT" lines, skipping blank lines and comments.
+func readHashes(name string) (map[string]int64, error) {
+	data, err := fixtures.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make(map[string]int64)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		i := strings.IndexByte(line, ':')
+		if i < 0 {
+			return nil, fmt.Errorf("hibptest: %s: invalid line %q", name, line)
+		}
+		count, err := strconv.ParseInt(line[i+1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("hibptest: %s: invalid count in %q", name, line)
+		}
+		hashes[strings.ToUpper(line[:i])] = count
+	}
+	return hashes, scanner.Err()
+}
+
+// dataClasses returns the sorted data classes of every breach.
+func dataClasses(breaches []*Breach) []string {
+	seen := make(map[string]bool)
+	var classes []string
+	for _, b := range breaches {
+		for _, class := range b.DataClasses {
+			if !seen[class] {
+				seen[class] = true
+				classes = append(classes, class)
+			}
+		}
+	}
+	sort.Strings(classes)
+	return classes
+}
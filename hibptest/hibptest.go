@@ -0,0 +1,472 @@
+// Package hibptest provides an in-process fake of the haveibeenpwned.com v3
+// API and the Pwned Passwords range API, for testing code that talks to HIBP
+// without network access or a real API key.
+//
+// The fake is seeded from fixtures modelled on the documented integration
+// test accounts, such as "account-exists@hibp-integration-tests.com" and
+// "multiple-breaches@hibp-integration-tests.com". Like the real API it
+// requires a User-Agent on every request and the `hibp-api-key` header on the
+// authenticated endpoints, and rate limiting or outages can be simulated by
+// injecting faults:
+//
+//	srv := hibptest.NewServer()
+//	defer srv.Close()
+//
+//	client := gopwned.NewClient(nil, hibptest.APIKey)
+//	client.BaseURL, _ = url.Parse(srv.BaseURL())
+//	client.PwnPwdURL, _ = url.Parse(srv.RangeURL())
+//
+//	srv.Fail("/api/v3/breachedaccount/", hibptest.Fault{Status: http.StatusTooManyRequests, RetryAfter: 2, Count: 1})
+package hibptest
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// Server is a fake HIBP API listening on a local address. The exported
+	// fields hold the data it serves; they are seeded by NewServer and may be
+	// changed before sending requests, but not while requests are served.
+	Server struct {
+		*httptest.Server
+
+		// APIKey is the only key accepted by the authenticated endpoints.
+		APIKey string
+
+		// Breaches is the breach catalogue, Accounts maps lower case accounts
+		// to their breaches and pastes, and Domains lists the domains the API
+		// key may search.
+		Breaches []*Breach
+		Accounts map[string]*Account
+		Domains  []string
+
+		// SHA1 and NTLM map upper case hashes to the number of times they
+		// were seen, for the range API.
+		SHA1 map[string]int64
+		NTLM map[string]int64
+
+		mu     sync.Mutex
+		faults []*fault
+		hits   map[string]int
+		rnd    *rand.Rand
+	}
+
+	// Fault makes matching requests fail with Status, typically 429 or 503.
+	// RetryAfter is sent as the "Retry-After" header in seconds if set. Count
+	// limits the number of requests that fail; 0 fails every request until
+	// the fault is cleared.
+	Fault struct {
+		Status     int
+		RetryAfter int
+		Count      int
+	}
+
+	fault struct {
+		prefix string
+		Fault
+		failed int
+	}
+
+	// apiError is the body of error responses of the HIBP API.
+	apiError struct {
+		StatusCode int    `json:"statusCode"`
+		Message    string `json:"message"`
+	}
+)
+
+const (
+	// APIKey is the key accepted by a new Server.
+	APIKey = "hibptest-api-key"
+
+	// Domain is the verified domain of the integration-test accounts.
+	Domain = "hibp-integration-tests.com"
+)
+
+// authenticated lists the API resources that require an API key.
+var authenticated = map[string]bool{
+	"breachedaccount":   true,
+	"pasteaccount":      true,
+	"breacheddomain":    true,
+	"subscribeddomains": true,
+	"subscription":      true,
+}
+
+// NewServer starts a fake seeded with the fixtures. The caller should call
+// Close when finished, to shut it down.
+func NewServer() *Server {
+	s := &Server{
+		APIKey:  APIKey,
+		Domains: []string{Domain},
+		hits:    make(map[string]int),
+		rnd:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	if err := s.loadFixtures(); err != nil {
+		panic(err)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// BaseURL returns the URL to use as the API base URL of a client.
+func (s *Server) BaseURL() string {
+	return s.URL + "/api/v3/"
+}
+
+// RangeURL returns the URL to use as the range API URL of a client.
+func (s *Server) RangeURL() string {
+	return s.URL + "/range/"
+}
+
+// Fail injects f for requests whose path starts with prefix, such as
+// "/api/v3/breachedaccount/" or "/range/". An empty prefix matches every
+// request. Faults are checked in the order they were added.
+func (s *Server) Fail(prefix string, f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &fault{prefix: prefix, Fault: f})
+}
+
+// ClearFaults removes every injected fault.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// Hits returns the number of requests received whose path starts with
+// prefix, including the ones that failed.
+func (s *Server) Hits(prefix string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for path, count := range s.hits {
+		if strings.HasPrefix(path, prefix) {
+			n += count
+		}
+	}
+	return n
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.hits[r.URL.Path]++
+	f := s.fault(r.URL.Path)
+	s.mu.Unlock()
+
+	if r.Header.Get("User-Agent") == "" {
+		writeError(w, http.StatusForbidden, "No user agent has been specified in the request.")
+		return
+	}
+	if f != nil {
+		if f.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(f.RetryAfter))
+		}
+		writeError(w, f.Status, fmt.Sprintf("Injected %d fault.", f.Status))
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, "The requested resource does not support this method.")
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/range/"):
+		s.serveRange(w, r, strings.TrimPrefix(r.URL.Path, "/range/"))
+	case strings.HasPrefix(r.URL.Path, "/api/v3/"):
+		s.serveAPI(w, r, strings.TrimPrefix(r.URL.Path, "/api/v3/"))
+	default:
+		writeError(w, http.StatusNotFound, "Not found.")
+	}
+}
+
+// fault returns the first fault matching path and counts it as used. It must
+// be called with s.mu held.
+func (s *Server) fault(path string) *fault {
+	for i, f := range s.faults {
+		if !strings.HasPrefix(path, f.prefix) {
+			continue
+		}
+		f.failed++
+		if f.Count > 0 && f.failed >= f.Count {
+			s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+		}
+		return f
+	}
+	return nil
+}
+
+func (s *Server) serveAPI(w http.ResponseWriter, r *http.Request, resource string) {
+	name, arg := resource, ""
+	if i := strings.IndexByte(resource, '/'); i >= 0 {
+		name, arg = resource[:i], resource[i+1:]
+	}
+
+	if authenticated[name] && r.Header.Get("hibp-api-key") != s.APIKey {
+		writeError(w, http.StatusUnauthorized, "Access denied due to invalid hibp-api-key.")
+		return
+	}
+
+	query := r.URL.Query()
+	switch {
+	case name == "breachedaccount" && arg != "":
+		s.breachedAccount(w, strings.ToLower(arg), query.Get("domain"),
+			boolParam(query.Get("truncateResponse"), true), boolParam(query.Get("includeUnverified"), true))
+	case name == "pasteaccount" && arg != "":
+		s.pasteAccount(w, strings.ToLower(arg))
+	case name == "breacheddomain" && arg != "":
+		s.breachedDomain(w, strings.ToLower(arg))
+	case name == "breaches" && arg == "":
+		writeJSON(w, s.breaches(query.Get("domain")))
+	case name == "breach" && arg != "":
+		if b := s.breach(arg); b != nil {
+			writeJSON(w, b)
+		} else {
+			writeError(w, http.StatusNotFound, "Not found.")
+		}
+	case name == "latestbreach" && arg == "":
+		s.latestBreach(w)
+	case name == "dataclasses" && arg == "":
+		writeJSON(w, dataClasses(s.Breaches))
+	case name == "subscribeddomains" && arg == "":
+		s.subscribedDomains(w)
+	case name == "subscription" && arg == "status":
+		writeJSON(w, map[string]interface{}{
+			"SubscriptionName":                "Pwned 1",
+			"Description":                     "Fake subscription of the hibptest package.",
+			"SubscribedUntil":                 "2099-12-31T00:00:00",
+			"Rpm":                             10,
+			"DomainSearchMaxBreachedAccounts": 25,
+		})
+	default:
+		writeError(w, http.StatusNotFound, "Not found.")
+	}
+}
+
+func (s *Server) breachedAccount(w http.ResponseWriter, account, domain string, truncate, unverified bool) {
+	if strings.TrimSpace(account) == "" {
+		writeError(w, http.StatusBadRequest, "The account does not comply with an acceptable format.")
+		return
+	}
+
+	var found []*Breach
+	if a := s.Accounts[account]; a != nil {
+		for _, name := range a.Breaches {
+			b := s.breach(name)
+			if b == nil || !unverified && !b.IsVerified {
+				continue
+			}
+			if domain != "" && !strings.EqualFold(b.Domain, domain) {
+				continue
+			}
+			found = append(found, b)
+		}
+	}
+	if len(found) == 0 {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+
+	if !truncate {
+		writeJSON(w, found)
+		return
+	}
+	names := make([]map[string]string, len(found))
+	for i, b := range found {
+		names[i] = map[string]string{"Name": b.Name}
+	}
+	writeJSON(w, names)
+}
+
+func (s *Server) pasteAccount(w http.ResponseWriter, account string) {
+	if !strings.Contains(account, "@") {
+		writeError(w, http.StatusBadRequest, "The account does not comply with an acceptable format.")
+		return
+	}
+
+	a := s.Accounts[account]
+	if a == nil || len(a.Pastes) == 0 {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	writeJSON(w, a.Pastes)
+}
+
+func (s *Server) breachedDomain(w http.ResponseWriter, domain string) {
+	if !s.verified(domain) {
+		writeError(w, http.StatusForbidden, "The domain has not been verified for this API key.")
+		return
+	}
+
+	aliases := s.aliases(domain)
+	if len(aliases) == 0 {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	writeJSON(w, aliases)
+}
+
+func (s *Server) subscribedDomains(w http.ResponseWriter) {
+	type subscribedDomain struct {
+		DomainName                 string
+		PwnCount                   int
+		PwnCountExcludingSpamLists int
+		NextSubscriptionRenewal    string
+	}
+
+	domains := make([]subscribedDomain, 0, len(s.Domains))
+	for _, domain := range s.Domains {
+		d := subscribedDomain{DomainName: domain, NextSubscriptionRenewal: "2099-12-31T00:00:00"}
+		for _, names := range s.aliases(domain) {
+			for _, name := range names {
+				d.PwnCount++
+				if b := s.breach(name); b != nil && !b.IsSpamList {
+					d.PwnCountExcludingSpamLists++
+				}
+			}
+		}
+		domains = append(domains, d)
+	}
+	writeJSON(w, domains)
+}
+
+// aliases maps the breached aliases of domain to the names of the active
+// breaches they appear in.
+func (s *Server) aliases(domain string) map[string][]string {
+	aliases := make(map[string][]string)
+	for account, a := range s.Accounts {
+		at := strings.LastIndexByte(account, '@')
+		if at < 0 || !strings.EqualFold(account[at+1:], domain) {
+			continue
+		}
+		for _, name := range a.Breaches {
+			if b := s.breach(name); b != nil {
+				aliases[account[:at]] = append(aliases[account[:at]], b.Name)
+			}
+		}
+	}
+	return aliases
+}
+
+func (s *Server) verified(domain string) bool {
+	for _, d := range s.Domains {
+		if strings.EqualFold(d, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) breaches(domain string) []*Breach {
+	breaches := make([]*Breach, 0, len(s.Breaches))
+	for _, b := range s.Breaches {
+		if domain == "" || strings.EqualFold(b.Domain, domain) {
+			breaches = append(breaches, b)
+		}
+	}
+	return breaches
+}
+
+func (s *Server) breach(name string) *Breach {
+	for _, b := range s.Breaches {
+		if strings.EqualFold(b.Name, name) {
+			return b
+		}
+	}
+	return nil
+}
+
+func (s *Server) latestBreach(w http.ResponseWriter) {
+	var latest *Breach
+	for _, b := range s.Breaches {
+		if latest == nil || b.AddedDate > latest.AddedDate {
+			latest = b
+		}
+	}
+	if latest == nil {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	writeJSON(w, latest)
+}
+
+// serveRange answers like api.pwnedpasswords.com: "SUFFIX:COUNT" lines
+// separated by CRLF, padded with zero counts if asked to.
+func (s *Server) serveRange(w http.ResponseWriter, r *http.Request, prefix string) {
+	if _, err := hex.DecodeString(prefix + "0"); len(prefix) != 5 || err != nil {
+		http.Error(w, "The hash prefix was not in a valid format", http.StatusBadRequest)
+		return
+	}
+	prefix = strings.ToUpper(prefix)
+
+	hashes, hashLen := s.SHA1, 40
+	if strings.EqualFold(r.URL.Query().Get("mode"), "ntlm") {
+		hashes, hashLen = s.NTLM, 32
+	}
+
+	var lines []string
+	seen := make(map[string]bool)
+	for hash, count := range hashes {
+		if strings.HasPrefix(hash, prefix) {
+			lines = append(lines, hash[5:]+":"+strconv.FormatInt(count, 10))
+			seen[hash[5:]] = true
+		}
+	}
+
+	if strings.EqualFold(r.Header.Get("Add-Padding"), "true") {
+		s.mu.Lock()
+		for target := 800 + s.rnd.Intn(201); len(lines) < target; {
+			suffix := s.randomHex(hashLen - 5)
+			if !seen[suffix] {
+				seen[suffix] = true
+				lines = append(lines, suffix+":0")
+			}
+		}
+		s.mu.Unlock()
+	}
+	sort.Strings(lines)
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(strings.Join(lines, "\r\n")))
+}
+
+// randomHex must be called with s.mu held.
+func (s *Server) randomHex(n int) string {
+	const digits = "0123456789ABCDEF"
+
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = digits[s.rnd.Intn(len(digits))]
+	}
+	return string(b)
+}
+
+func boolParam(value string, def bool) bool {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return def
+	}
+	return b
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(apiError{StatusCode: status, Message: message})
+}
@@ -0,0 +1,173 @@
+package hibptest_test
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	gopwned "github.com/mavjs/goPwned"
+	"github.com/mavjs/goPwned/hibptest"
+	"github.com/mavjs/goPwned/password"
+)
+
+func setupClient(t *testing.T, token string) (*hibptest.Server, *gopwned.Client) {
+	srv := hibptest.NewServer()
+	t.Cleanup(srv.Close)
+
+	client := gopwned.NewClient(nil, token)
+	client.BaseURL, _ = url.Parse(srv.BaseURL())
+	client.PwnPwdURL, _ = url.Parse(srv.RangeURL())
+	return srv, client
+}
+
+func names(breaches []*gopwned.Breach) []string {
+	var names []string
+	for _, b := range breaches {
+		names = append(names, b.Name)
+	}
+	return names
+}
+
+func TestIntegrationAccounts(t *testing.T) {
+	assert := assert.New(t)
+
+	_, client := setupClient(t, hibptest.APIKey)
+
+	tests := map[string][]string{
+		"account-exists":               {"Adobe"},
+		"multiple-breaches":            {"Adobe", "Gawker", "Stratfor"},
+		"not-active-and-active-breach": {"Adobe"},
+		"not-active-breach":            nil,
+		"opt-out":                      nil,
+		"sensitive-breach":             {"AshleyMadison"},
+		"spam-list-only":               {"OnlinerSpambot"},
+	}
+	for alias, want := range tests {
+		got, err := client.GetAccountBreaches(alias+"@"+hibptest.Domain, "", true, true)
+		if want == nil {
+			assert.Equal(gopwned.ErrNotFound, err, "[TestIntegrationAccounts] Expected %s not to be found.", alias)
+			continue
+		}
+		if assert.NoError(err, "[TestIntegrationAccounts] %s", alias) {
+			assert.Equal(want, names(got), "[TestIntegrationAccounts] Unexpected breaches of %s.", alias)
+			assert.Empty(got[0].Title, "[TestIntegrationAccounts] Expected a truncated response.")
+		}
+	}
+
+	got, err := client.GetAccountBreaches("multiple-breaches@"+hibptest.Domain, "gawker.com", false, true)
+	if assert.NoError(err) && assert.Len(got, 1) {
+		assert.Equal("Gawker", got[0].Title, "[TestIntegrationAccounts] Expected the full breach filtered by domain.")
+	}
+
+	pastes, err := client.GetAccountPastes("paste-sensitive-breach@" + hibptest.Domain)
+	if assert.NoError(err) && assert.Len(pastes, 1) {
+		assert.Equal("uQNGpAxp", pastes[0].ID)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	assert := assert.New(t)
+
+	_, client := setupClient(t, "wrong-key")
+
+	_, err := client.GetAccountBreaches("account-exists@"+hibptest.Domain, "", true, false)
+	assert.EqualError(err, "Unauthorised — the API key provided was not valid", "[TestAuthentication] Expected a wrong key to be rejected.")
+
+	_, err = client.GetBreachedSites("")
+	assert.NoError(err, "[TestAuthentication] Expected the catalogue not to require a key.")
+
+	client.UserAgent = ""
+	_, err = client.GetBreachedSites("")
+	assert.EqualError(err, "Forbidden — no user agent has been specified in the request", "[TestAuthentication] Expected a missing User-Agent to be rejected.")
+}
+
+func TestCatalogue(t *testing.T) {
+	assert := assert.New(t)
+
+	srv, client := setupClient(t, hibptest.APIKey)
+
+	breaches, err := client.GetBreachedSites("adobe.com")
+	if assert.NoError(err) {
+		assert.Equal([]string{"Adobe"}, names(breaches))
+	}
+
+	breach, err := client.GetABreachedSite("stratfor")
+	if assert.NoError(err) {
+		assert.Equal("Stratfor", breach.Name)
+	}
+	_, err = client.GetABreachedSite("DoesNotExist")
+	assert.Equal(gopwned.ErrNotFound, err)
+
+	latest, err := client.GetLatestBreach()
+	if assert.NoError(err) {
+		assert.Equal("OnlinerSpambot", latest.Name, "[TestCatalogue] Expected the most recently added breach.")
+	}
+
+	classes, err := client.GetDataClasses()
+	if assert.NoError(err) {
+		assert.Contains(*classes, "Password hints")
+	}
+
+	aliases, err := client.GetDomainBreaches(hibptest.Domain)
+	if assert.NoError(err) {
+		assert.Equal([]string{"Adobe", "Gawker", "Stratfor"}, aliases["multiple-breaches"])
+		assert.NotContains(aliases, "not-active-breach", "[TestCatalogue] Expected inactive breaches to be left out.")
+	}
+	_, err = client.GetDomainBreaches("example.com")
+	assert.EqualError(err, "Forbidden — no user agent has been specified in the request")
+
+	assert.Equal(1, srv.Hits("/api/v3/breaches"))
+}
+
+func TestRange(t *testing.T) {
+	assert := assert.New(t)
+
+	_, client := setupClient(t, "")
+
+	count, err := password.Count(client, "P@ssw0rd")
+	if assert.NoError(err) {
+		assert.Equal(int64(83129), count)
+	}
+
+	body, err := client.GetPwnedPasswords("21BD1", true)
+	if assert.NoError(err) {
+		lines := strings.Split(string(body), "\r\n")
+		assert.True(len(lines) >= 800 && len(lines) <= 1000, "[TestRange] Expected a padded response. Got %d lines.", len(lines))
+	}
+
+	_, err = client.GetPwnedPasswords("XYZ", false)
+	assert.EqualError(err, "Bad request — the account does not comply with an acceptable format (i.e. it's an empty string)")
+}
+
+func TestFaults(t *testing.T) {
+	assert := assert.New(t)
+
+	srv, client := setupClient(t, hibptest.APIKey)
+
+	srv.Fail("/api/v3/breachedaccount/", hibptest.Fault{Status: http.StatusTooManyRequests, RetryAfter: 2, Count: 2})
+	for i := 0; i < 2; i++ {
+		_, err := client.GetAccountBreaches("account-exists@"+hibptest.Domain, "", true, false)
+		assert.EqualError(err, "Too many requests — the rate limit has been exceeded", "[TestFaults] Expected the injected 429.")
+	}
+	_, err := client.GetAccountBreaches("account-exists@"+hibptest.Domain, "", true, false)
+	assert.NoError(err, "[TestFaults] Expected the fault to be used up.")
+
+	srv.Fail("", hibptest.Fault{Status: http.StatusServiceUnavailable})
+	_, err = client.GetBreachedSites("")
+	assert.EqualError(err, "Service unavailable — usually returned by Cloudflare if the underlying service is not available")
+	_, err = client.GetPwnedPasswords("21BD1", false)
+	assert.Error(err)
+
+	srv.ClearFaults()
+	_, err = client.GetBreachedSites("")
+	assert.NoError(err)
+
+	resp, err := http.Get(srv.URL + "/api/v3/breaches")
+	if assert.NoError(err) {
+		resp.Body.Close()
+		assert.Equal(http.StatusOK, resp.StatusCode)
+	}
+}
@@ -0,0 +1,167 @@
+package gopwned
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type (
+	// KeyPool spreads the authenticated requests of a Client over several
+	// API keys, in proportion to the rate limit of each. A key answered with
+	// 401 is taken out of rotation and the request is sent again with
+	// another key; a 429 holds the key back for its "Retry-After". A KeyPool
+	// is safe for concurrent use and may be shared by several clients.
+	KeyPool struct {
+		mu   sync.Mutex
+		keys []*poolKey
+	}
+
+	// KeyUsage reports the use of a key of a pool. It never holds the key.
+	KeyUsage struct {
+		Name        string
+		RPM         int
+		Requests    int64
+		RateLimited int64
+		Removed     bool
+	}
+
+	poolKey struct {
+		key     string
+		limiter *Limiter
+		usage   KeyUsage
+	}
+)
+
+// ErrNoKeys is returned for authenticated requests when every key of the
+// pool has been removed.
+var ErrNoKeys = errors.New("no usable API key left in the pool")
+
+// NewKeyPool creates an empty key pool.
+func NewKeyPool() *KeyPool {
+	return &KeyPool{}
+}
+
+// Add adds key to the pool under name, used to report its usage, with the
+// rate limit of its subscription in requests per minute, which must be
+// positive.
+func (p *KeyPool) Add(name, key string, rpm int) error {
+	if rpm <= 0 {
+		return fmt.Errorf("key %s: rate limit must be positive, got %d requests per minute", name, rpm)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys = append(p.keys, &poolKey{key: key, limiter: NewLimiter(rpm), usage: KeyUsage{Name: name, RPM: rpm}})
+	return nil
+}
+
+// Usage returns the usage of every key, in the order they were added.
+func (p *KeyPool) Usage() []KeyUsage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	usage := make([]KeyUsage, len(p.keys))
+	for i, k := range p.keys {
+		usage[i] = k.usage
+	}
+	return usage
+}
+
+// acquire books a slot on the key that is free the soonest, preferring the
+// key with the higher rate limit on ties, and waits for it. The request is
+// only counted once the wait is over.
+func (p *KeyPool) acquire(ctx context.Context) (*poolKey, error) {
+	p.mu.Lock()
+	var best *poolKey
+	var bestReady time.Time
+	now := time.Now()
+	for _, k := range p.keys {
+		if k.usage.Removed {
+			continue
+		}
+		ready := k.limiter.ready()
+		if ready.Before(now) {
+			ready = now
+		}
+		if best == nil || ready.Before(bestReady) || ready.Equal(bestReady) && k.usage.RPM > best.usage.RPM {
+			best, bestReady = k, ready
+		}
+	}
+	if best == nil {
+		p.mu.Unlock()
+		return nil, ErrNoKeys
+	}
+	delay := best.limiter.Reserve()
+	p.mu.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	p.mu.Lock()
+	best.usage.Requests++
+	p.mu.Unlock()
+	return best, nil
+}
+
+// release records the response to a request sent with k.
+func (p *KeyPool) release(k *poolKey, resp *http.Response) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		k.usage.Removed = true
+	case http.StatusTooManyRequests:
+		k.usage.RateLimited++
+		if d := retryAfter(resp.Header.Get("Retry-After")); d > 0 {
+			k.limiter.pause(d)
+		}
+	}
+}
+
+// doPooled sends an authenticated request with a key of the client's pool,
+// failing over to the next key while keys are answered with 401.
+func (c *Client) doPooled(req *http.Request) (*http.Response, error) {
+	for {
+		k, err := c.Keys.acquire(req.Context())
+		if err != nil {
+			return nil, err
+		}
+
+		keyed := req.Clone(req.Context())
+		keyed.Header.Set("hibp-api-key", k.key)
+		resp, err := c.do(keyed)
+		if err != nil {
+			return nil, err
+		}
+		c.Keys.release(k, resp)
+		if resp.StatusCode != http.StatusUnauthorized || !c.Keys.usable() {
+			return resp, nil
+		}
+		closeBody(resp.Body)
+	}
+}
+
+// usable reports whether any key is left in rotation.
+func (p *KeyPool) usable() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, k := range p.keys {
+		if !k.usage.Removed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package gopwned
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mavjs/goPwned/hibptest"
+)
+
+func setupKeyPool(t *testing.T) (*hibptest.Server, *Client) {
+	srv := hibptest.NewServer()
+	t.Cleanup(srv.Close)
+
+	client := NewClient(nil, "")
+	client.BaseURL, _ = url.Parse(srv.BaseURL())
+	client.Keys = NewKeyPool()
+	return srv, client
+}
+
+func TestKeyPoolFailover(t *testing.T) {
+	assert := assert.New(t)
+	_, client := setupKeyPool(t)
+	client.Keys.Add("revoked", "revoked-key", 6000)
+	client.Keys.Add("team", hibptest.APIKey, 3000)
+
+	for i := 0; i < 2; i++ {
+		breaches, err := client.GetAccountBreaches("account-exists@"+hibptest.Domain, "", true, false)
+		assert.NoError(err, "[TestKeyPoolFailover] Expected the request to fail over to a valid key.")
+		assert.Len(breaches, 1)
+	}
+
+	assert.Equal([]KeyUsage{
+		{Name: "revoked", RPM: 6000, Requests: 1, Removed: true},
+		{Name: "team", RPM: 3000, Requests: 2},
+	}, client.Keys.Usage(), "[TestKeyPoolFailover] Expected the revoked key to be taken out of rotation.")
+}
+
+func TestKeyPoolExhausted(t *testing.T) {
+	assert := assert.New(t)
+	_, client := setupKeyPool(t)
+	client.Keys.Add("revoked", "revoked-key", 6000)
+
+	_, err := client.GetAccountBreaches("account-exists@"+hibptest.Domain, "", true, false)
+	assert.EqualError(err, respCodes[http.StatusUnauthorized], "[TestKeyPoolExhausted] Expected the 401 of the last key.")
+
+	_, err = client.GetAccountBreaches("account-exists@"+hibptest.Domain, "", true, false)
+	assert.Equal(ErrNoKeys, err, "[TestKeyPoolExhausted] Expected an error once every key is removed.")
+
+	_, err = client.GetDataClasses()
+	assert.NoError(err, "[TestKeyPoolExhausted] Expected unauthenticated endpoints to be unaffected.")
+}
+
+func TestKeyPoolRateLimited(t *testing.T) {
+	assert := assert.New(t)
+	srv, client := setupKeyPool(t)
+	client.Keys.Add("first", hibptest.APIKey, 6000)
+	client.Keys.Add("second", hibptest.APIKey, 3000)
+	srv.Fail("/api/v3/breachedaccount/", hibptest.Fault{Status: http.StatusTooManyRequests, RetryAfter: 60, Count: 1})
+
+	_, err := client.GetAccountBreaches("account-exists@"+hibptest.Domain, "", true, false)
+	assert.Error(err)
+	for i := 0; i < 3; i++ {
+		_, err = client.GetAccountBreaches("account-exists@"+hibptest.Domain, "", true, false)
+		assert.NoError(err)
+	}
+
+	usage := client.Keys.Usage()
+	assert.Equal(int64(1), usage[0].RateLimited, "[TestKeyPoolRateLimited] Expected the 429 to be tracked on the key.")
+	assert.Equal(int64(1), usage[0].Requests, "[TestKeyPoolRateLimited] Expected the rate limited key to be held back.")
+	assert.Equal(int64(3), usage[1].Requests)
+}
+
+func TestKeyPoolWeights(t *testing.T) {
+	assert := assert.New(t)
+
+	pool := NewKeyPool()
+	pool.Add("large", "large-key", 60000)
+	pool.Add("small", "small-key", 30000)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			k, err := pool.acquire(context.Background())
+			if assert.NoError(err) {
+				pool.release(k, &http.Response{StatusCode: http.StatusOK})
+			}
+		}()
+	}
+	wg.Wait()
+
+	usage := pool.Usage()
+	assert.InDelta(20, usage[0].Requests, 2, "[TestKeyPoolWeights] Expected requests in proportion to the RPM of each key.")
+	assert.InDelta(10, usage[1].Requests, 2)
+}
+
+func TestKeyPoolAddInvalidRPM(t *testing.T) {
+	assert := assert.New(t)
+
+	pool := NewKeyPool()
+	assert.Error(pool.Add("zero", "zero-key", 0), "[TestKeyPoolAddInvalidRPM] Expected a key without a rate limit to be rejected.")
+	assert.Error(pool.Add("negative", "negative-key", -1))
+	assert.NoError(pool.Add("team", "team-key", 60))
+	assert.Len(pool.Usage(), 1, "[TestKeyPoolAddInvalidRPM] Expected only the valid key to be added.")
+}
+
+func TestKeyPoolCancelledWait(t *testing.T) {
+	assert := assert.New(t)
+
+	pool := NewKeyPool()
+	pool.Add("team", "team-key", 1)
+	if _, err := pool.acquire(context.Background()); err != nil {
+		t.Fatalf("[TestKeyPoolCancelledWait] returned error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := pool.acquire(ctx)
+	assert.Equal(context.DeadlineExceeded, err)
+	assert.Equal(int64(1), pool.Usage()[0].Requests, "[TestKeyPoolCancelledWait] Expected a cancelled wait not to be counted.")
+}
@@ -0,0 +1,119 @@
+package gopwned
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+type (
+	// Doer sends an HTTP request and returns its response. *http.Client
+	// satisfies it.
+	Doer interface {
+		Do(req *http.Request) (*http.Response, error)
+	}
+
+	// DoerFunc adapts a function to a Doer.
+	DoerFunc func(req *http.Request) (*http.Response, error)
+
+	// Middleware wraps the Doer that sends the requests of a Client, to
+	// change requests on their way out or responses on their way back.
+	Middleware func(next Doer) Doer
+)
+
+// Do calls f(req).
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Use appends middleware to the chain that sends every request of the
+// client, to both the HIBP and the Pwned Passwords APIs. The first
+// middleware added is the outermost: it sees requests first and responses
+// last. The chain runs after the client has answered from its cache and
+// added the API key, so a typical order is:
+//
+//	client.Use(
+//		gopwned.Header("X-Gopwned-Client", "billing"), // change requests
+//		gopwned.Retry(3, time.Second),                 // retry 429 and 503
+//		gopwned.RoundTrip(recorder),                   // send, e.g. through replay
+//	)
+//
+// Use is not safe for concurrent use: call it while setting up the client,
+// before its first request. Copies made by WithResponse afterwards keep the
+// chain they were made with.
+func (c *Client) Use(middleware ...Middleware) {
+	// Copy the chain, a copy of the client may share its backing array.
+	c.middleware = append(c.middleware[:len(c.middleware):len(c.middleware)], middleware...)
+}
+
+// do sends req through the middleware chain.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	var d Doer = c.client
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		d = c.middleware[i](d)
+	}
+	return d.Do(req)
+}
+
+// Header returns middleware that sets a header on every request, e.g. to
+// identify the calling service to gopwned-proxy.
+func Header(key, value string) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			req.Header.Set(key, value)
+			return next.Do(req)
+		})
+	}
+}
+
+// RoundTrip returns middleware that ends the chain by sending requests with
+// rt instead of the client's *http.Client, e.g. a replay.Transport.
+func RoundTrip(rt http.RoundTripper) Middleware {
+	return func(Doer) Doer {
+		return DoerFunc(rt.RoundTrip)
+	}
+}
+
+// Retry returns middleware that retries requests answered with 429 or 503
+// up to attempts times. It waits as long as the "Retry-After" header asks
+// or, without it, backoff doubled on every attempt.
+func Retry(attempts int, backoff time.Duration) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			for attempt := 0; ; attempt++ {
+				resp, err := next.Do(req)
+				if err != nil || attempt >= attempts || !retryable(resp.StatusCode) {
+					return resp, err
+				}
+
+				wait := backoff << attempt
+				if d := retryAfter(resp.Header.Get("Retry-After")); d > 0 {
+					wait = d
+				}
+				status := resp.StatusCode
+				closeBody(resp.Body)
+
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-req.Context().Done():
+					timer.Stop()
+					return nil, req.Context().Err()
+				}
+				retried(req.Context(), status)
+			}
+		})
+	}
+}
+
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+// retried counts a retry on the call that sent the request, if any.
+func retried(ctx context.Context, status int) {
+	if o, ok := ctx.Value(callKey{}).(*call); ok {
+		o.retry(status)
+	}
+}
@@ -0,0 +1,145 @@
+package gopwned
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mavjs/goPwned/hibptest"
+)
+
+func TestUseOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next Doer) Doer {
+			return DoerFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name+" request")
+				resp, err := next.Do(req)
+				order = append(order, name+" response")
+				return resp, err
+			})
+		}
+	}
+
+	client := NewClient(nil, "")
+	client.Use(trace("outer"), trace("middle"))
+	client.Use(trace("inner"), RoundTrip(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		order = append(order, "send "+req.URL.Path)
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(`["Passwords"]`))}, nil
+	})))
+
+	got, err := client.GetDataClasses()
+	if assert.NoError(err) {
+		assert.Equal(&DataClasses{"Passwords"}, got)
+	}
+	assert.Equal([]string{
+		"outer request", "middle request", "inner request",
+		"send /api/v3/dataclasses",
+		"inner response", "middle response", "outer response",
+	}, order, "[TestUseOrder] Expected the first middleware to be the outermost.")
+}
+
+func TestUseCopies(t *testing.T) {
+	assert := assert.New(t)
+
+	var got http.Header
+	client := NewClient(&http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		got = req.Header
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(`["Passwords"]`))}, nil
+	})}, "")
+	// Three calls leave spare capacity in the chain.
+	client.Use(Header("X-A", "1"))
+	client.Use(Header("X-B", "1"))
+	client.Use(Header("X-C", "1"))
+
+	var meta Response
+	cp := client.WithResponse(&meta)
+	cp.Use(Header("X-Copy", "1"))
+	client.Use(Header("X-Original", "1"))
+
+	_, err := cp.GetDataClasses()
+	assert.NoError(err)
+	assert.Equal("1", got.Get("X-Copy"), "[TestUseCopies] Expected the chain of the copy to be kept.")
+	assert.Empty(got.Get("X-Original"), "[TestUseCopies] Expected the original not to change the chain of the copy.")
+
+	_, err = client.GetDataClasses()
+	assert.NoError(err)
+	assert.Equal("1", got.Get("X-Original"))
+	assert.Empty(got.Get("X-Copy"), "[TestUseCopies] Expected the copy not to change the chain of the original.")
+}
+
+// roundTripFunc adapts a function to an http.RoundTripper.
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestHeaderMiddleware(t *testing.T) {
+	assert := assert.New(t)
+
+	var got http.Header
+	client := NewClient(nil, "")
+	client.Use(Header("X-Gopwned-Client", "billing"), RoundTrip(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		got = req.Header
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("ABC:1"))}, nil
+	})))
+
+	_, err := client.GetPwnedPasswords("21BD1", true)
+	assert.NoError(err)
+	assert.Equal("billing", got.Get("X-Gopwned-Client"), "[TestHeaderMiddleware] Expected the header on range requests.")
+	assert.Equal("true", got.Get("Add-Padding"))
+}
+
+func TestRetryMiddleware(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := hibptest.NewServer()
+	defer srv.Close()
+
+	metrics := &testMetrics{counts: make(map[string]int)}
+	client := NewClient(nil, hibptest.APIKey)
+	client.BaseURL, _ = url.Parse(srv.BaseURL())
+	client.Metrics = metrics
+	client.Use(Retry(2, time.Millisecond))
+
+	srv.Fail("/api/v3/breachedaccount/", hibptest.Fault{Status: http.StatusTooManyRequests, Count: 1})
+	srv.Fail("/api/v3/breachedaccount/", hibptest.Fault{Status: http.StatusServiceUnavailable, Count: 1})
+	got, err := client.GetAccountBreaches("account-exists@"+hibptest.Domain, "", true, false)
+	if assert.NoError(err, "[TestRetryMiddleware] Expected the request to succeed after retrying.") {
+		assert.Len(got, 1)
+	}
+	assert.Equal(3, srv.Hits("/api/v3/breachedaccount/"))
+	assert.Equal(1, metrics.counts["retries breachedaccount 429"])
+	assert.Equal(1, metrics.counts["rate_limited breachedaccount 429"])
+	assert.Equal(1, metrics.counts["retries breachedaccount 503"])
+
+	srv.Fail("/api/v3/breaches", hibptest.Fault{Status: http.StatusServiceUnavailable})
+	_, err = client.GetBreachedSites("")
+	assert.Equal(respCodes[503], err.Error(), "[TestRetryMiddleware] Expected the last response once attempts are used up.")
+	assert.Equal(3, srv.Hits("/api/v3/breaches"))
+}
+
+func TestRetryCanceled(t *testing.T) {
+	calls := 0
+	retry := Retry(5, time.Hour)(DoerFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://example.com", nil)
+
+	_, err := retry.Do(req)
+	assert.Equal(t, context.DeadlineExceeded, err, "[TestRetryCanceled] Expected to stop waiting once the context is done.")
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,167 @@
+// Package notify pushes breach findings out to webhooks, chat services,
+// email and syslog. Every sink renders its message from a Template built on
+// the fields of the finding and its breach.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	gopwned "github.com/mavjs/goPwned"
+)
+
+type (
+	// TargetKind tells whether a finding is about an account or a domain.
+	TargetKind string
+
+	// Finding is a watched target showing up in a breach.
+	Finding struct {
+		Kind    TargetKind      `json:"kind"`
+		Target  string          `json:"target"`
+		Alias   string          `json:"alias,omitempty"`
+		Breach  *gopwned.Breach `json:"breach"`
+		FoundAt time.Time       `json:"found_at"`
+	}
+
+	// Sink receives findings that were not seen before.
+	Sink interface {
+		Notify(f *Finding) error
+	}
+
+	// SinkFunc adapts an ordinary function to a Sink.
+	SinkFunc func(f *Finding) error
+
+	// Writer is a Sink writing every finding as a line of JSON.
+	Writer struct {
+		mu sync.Mutex
+		w  io.Writer
+	}
+
+	// Template renders the subject and body of a message from a Finding.
+	Template struct {
+		Subject *template.Template
+		Body    *template.Template
+	}
+)
+
+// Kinds of watched targets.
+const (
+	AccountTarget TargetKind = "account"
+	DomainTarget  TargetKind = "domain"
+)
+
+const (
+	defaultSubject = `{{.Target}} found in the {{.Breach | title}} breach`
+
+	defaultBody = `{{if .Alias}}{{.Alias}}@{{end}}{{.Target}} was found in the {{.Breach | title}} breach` +
+		`{{with .Breach.Domain}} ({{.}}){{end}}.` +
+		`{{with .Breach.BreachDate}}
+Breach date: {{.}}{{end}}` +
+		`{{with .Breach.PwnCount}}
+Accounts affected: {{.}}{{end}}` +
+		`{{with .Breach.DataClasses | classes}}
+Compromised data: {{.}}{{end}}` +
+		`{{if .Breach.IsSensitive}}
+This breach is flagged as sensitive.{{end}}`
+)
+
+var (
+	templateFuncs = template.FuncMap{
+		"title": func(b *gopwned.Breach) string {
+			if b.Title != "" {
+				return b.Title
+			}
+			return b.Name
+		},
+		"classes": func(dc *gopwned.DataClasses) string {
+			if dc == nil {
+				return ""
+			}
+			return strings.Join(*dc, ", ")
+		},
+	}
+
+	// DefaultTemplate is used by every sink that has no Template set.
+	DefaultTemplate = MustParseTemplate(defaultSubject, defaultBody)
+
+	// DefaultHTTPClient is used by the webhook based sinks that have no
+	// Client set.
+	DefaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+// Key identifies a finding across restarts.
+func (f *Finding) Key() string {
+	key := string(f.Kind) + ":" + f.Target
+	if f.Alias != "" {
+		key += ":" + f.Alias
+	}
+	return key + ":" + f.Breach.Name
+}
+
+// Notify calls fn(f).
+func (fn SinkFunc) Notify(f *Finding) error {
+	return fn(f)
+}
+
+// NewWriter creates a sink writing JSON lines to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Notify writes f to the underlying writer.
+func (s *Writer) Notify(f *Finding) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return json.NewEncoder(s.w).Encode(f)
+}
+
+// ParseTemplate parses the subject and body templates. Both are executed
+// with the *Finding as data, and can use the "title" function to get the
+// title of a breach and "classes" to list its data classes.
+func ParseTemplate(subject, body string) (*Template, error) {
+	s, err := template.New("subject").Funcs(templateFuncs).Parse(subject)
+	if err != nil {
+		return nil, err
+	}
+	b, err := template.New("body").Funcs(templateFuncs).Parse(body)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{Subject: s, Body: b}, nil
+}
+
+// MustParseTemplate is like ParseTemplate but panics if a template is invalid.
+func MustParseTemplate(subject, body string) *Template {
+	t, err := ParseTemplate(subject, body)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Render executes the templates for f.
+func (t *Template) Render(f *Finding) (subject, body string, err error) {
+	var sb, bb bytes.Buffer
+	if err := t.Subject.Execute(&sb, f); err != nil {
+		return "", "", err
+	}
+	if err := t.Body.Execute(&bb, f); err != nil {
+		return "", "", err
+	}
+	return sb.String(), bb.String(), nil
+}
+
+// render uses t, or the default template if t is nil.
+func render(t *Template, f *Finding) (subject, body string, err error) {
+	if t == nil {
+		t = DefaultTemplate
+	}
+	return t.Render(f)
+}
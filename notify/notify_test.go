@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	gopwned "github.com/mavjs/goPwned"
+)
+
+func setupFinding() *Finding {
+	return &Finding{
+		Kind:   DomainTarget,
+		Target: "bar.com",
+		Alias:  "foo",
+		Breach: &gopwned.Breach{
+			Name:        "Adobe",
+			Title:       "Adobe",
+			Domain:      "adobe.com",
+			BreachDate:  "2013-10-04",
+			PwnCount:    152445165,
+			DataClasses: &gopwned.DataClasses{"Email addresses", "Passwords"},
+		},
+		FoundAt: time.Date(2022, 3, 13, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestFindingKey(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("domain:bar.com:foo:Adobe", setupFinding().Key())
+	assert.Equal("account:foo@bar.com:Adobe", (&Finding{Kind: AccountTarget, Target: "foo@bar.com", Breach: &gopwned.Breach{Name: "Adobe"}}).Key())
+}
+
+func TestDefaultTemplate(t *testing.T) {
+	assert := assert.New(t)
+
+	subject, body, err := DefaultTemplate.Render(setupFinding())
+	if err != nil {
+		t.Fatalf("[TestDefaultTemplate] returned error: %v", err)
+	}
+
+	assert.Equal("bar.com found in the Adobe breach", subject)
+	assert.Equal("foo@bar.com was found in the Adobe breach (adobe.com).\n"+
+		"Breach date: 2013-10-04\n"+
+		"Accounts affected: 152445165\n"+
+		"Compromised data: Email addresses, Passwords", body)
+}
+
+func TestCustomTemplate(t *testing.T) {
+	tmpl, err := ParseTemplate(`{{.Breach | title}}`, `{{.Target}}: {{.Breach.DataClasses | classes}}`)
+	if err != nil {
+		t.Fatalf("[TestCustomTemplate] returned error: %v", err)
+	}
+
+	_, body, err := tmpl.Render(setupFinding())
+	if err != nil {
+		t.Fatalf("[TestCustomTemplate] returned error: %v", err)
+	}
+	assert.Equal(t, "bar.com: Email addresses, Passwords", body)
+
+	_, err = ParseTemplate(`{{.Target`, "")
+	assert.Error(t, err, "[TestCustomTemplate] Expected an error for an invalid template.")
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := NewWriter(&buf).Notify(&Finding{Kind: AccountTarget, Target: "foo@bar.com", Breach: &gopwned.Breach{Name: "Adobe"}}); err != nil {
+		t.Fatalf("[TestWriter] returned error: %v", err)
+	}
+	assert.Contains(t, buf.String(), `"target":"foo@bar.com","breach":{"Name":"Adobe"}`)
+}
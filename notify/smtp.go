@@ -0,0 +1,42 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Email sends every finding as a plain text email through an SMTP server.
+type Email struct {
+	// Addr is the "host:port" of the SMTP server.
+	Addr string
+	// Auth is used if the server supports it, e.g. smtp.PlainAuth.
+	Auth     smtp.Auth
+	From     string
+	To       []string
+	Template *Template
+}
+
+// Notify mails f to the configured recipients.
+func (e *Email) Notify(f *Finding) error {
+	subject, message, err := render(e.Template, f)
+	if err != nil {
+		return err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.Replace(message, "\n", "\r\n", -1))
+	msg.WriteString("\r\n")
+
+	return smtp.SendMail(e.Addr, e.Auth, e.From, e.To, msg.Bytes())
+}
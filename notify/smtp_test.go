@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"bufio"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSMTP accepts a single mail and sends its DATA on the returned channel.
+func fakeSMTP(t *testing.T) (string, <-chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	data := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		tp := textproto.NewConn(conn)
+		tp.PrintfLine("220 localhost ESMTP fake")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.Fields(line)[0]); cmd {
+			case "EHLO", "HELO":
+				tp.PrintfLine("250 localhost")
+			case "MAIL", "RCPT", "RSET", "NOOP":
+				tp.PrintfLine("250 OK")
+			case "DATA":
+				tp.PrintfLine("354 send data")
+				lines, _ := tp.ReadDotLines()
+				data <- strings.Join(lines, "\n")
+				tp.PrintfLine("250 OK")
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				return
+			default:
+				tp.PrintfLine("502 not implemented")
+			}
+		}
+	}()
+
+	return ln.Addr().String(), data
+}
+
+func TestEmail(t *testing.T) {
+	assert := assert.New(t)
+
+	addr, data := fakeSMTP(t)
+	sink := &Email{Addr: addr, From: "gopwned@bar.com", To: []string{"security@bar.com"}}
+
+	if err := sink.Notify(setupFinding()); err != nil {
+		t.Fatalf("[TestEmail] returned error: %v", err)
+	}
+
+	msg, err := textproto.NewReader(bufio.NewReader(strings.NewReader(<-data + "\n"))).ReadMIMEHeader()
+	if err != nil && err.Error() != "EOF" {
+		t.Fatalf("[TestEmail] unable to parse message: %v", err)
+	}
+	assert.Equal("security@bar.com", msg.Get("To"))
+	assert.Equal("bar.com found in the Adobe breach", msg.Get("Subject"))
+	assert.Equal("text/plain; charset=utf-8", msg.Get("Content-Type"))
+}
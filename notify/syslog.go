@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// Syslog sends every finding as an RFC 5424 message to a syslog server over
+// UDP or TCP. It talks the protocol directly, so it works on every platform.
+type Syslog struct {
+	// Network is "udp" or "tcp", Addr the "host:port" of the server.
+	Network string
+	Addr    string
+	// Tag is the APP-NAME of the message, defaults to "gopwned".
+	Tag string
+	// Facility defaults to LOG_AUTH (4), findings are sent with severity
+	// warning.
+	Facility int
+	Template *Template
+}
+
+const (
+	syslogFacilityAuth    = 4
+	syslogSeverityWarning = 4
+)
+
+// Notify sends f to the syslog server.
+func (s *Syslog) Notify(f *Finding) error {
+	_, message, err := render(s.Template, f)
+	if err != nil {
+		return err
+	}
+
+	facility := s.Facility
+	if facility == 0 {
+		facility = syslogFacilityAuth
+	}
+	tag := s.Tag
+	if tag == "" {
+		tag = "gopwned"
+	}
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+
+	// Syslog messages are a single line.
+	message = strings.Replace(message, "\n", " ", -1)
+	line := fmt.Sprintf("<%d>1 %s %s %s %d - - %s",
+		facility*8+syslogSeverityWarning, time.Now().UTC().Format(time.RFC3339), hostname, tag, os.Getpid(), message)
+
+	conn, err := net.DialTimeout(s.Network, s.Addr, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if s.Network == "tcp" {
+		// RFC 6587 octet counting framing.
+		line = fmt.Sprintf("%d %s", len(line), line)
+	}
+	_, err = conn.Write([]byte(line))
+	return err
+}
@@ -0,0 +1,34 @@
+package notify
+
+import (
+	"net"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyslog(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("[TestSyslog] unable to listen: %v", err)
+	}
+	defer conn.Close()
+
+	sink := &Syslog{Network: "udp", Addr: conn.LocalAddr().String()}
+	if err := sink.Notify(setupFinding()); err != nil {
+		t.Fatalf("[TestSyslog] returned error: %v", err)
+	}
+
+	buf := make([]byte, 2048)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("[TestSyslog] unable to read message: %v", err)
+	}
+
+	// LOG_AUTH (4) * 8 + LOG_WARNING (4) = 36
+	pattern := regexp.MustCompile(`^<36>1 \S+ \S+ gopwned \d+ - - foo@bar\.com was found in the Adobe breach \(adobe\.com\)\. Breach date: 2013-10-04 `)
+	assert.Regexp(t, pattern, string(buf[:n]))
+}
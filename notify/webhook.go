@@ -0,0 +1,157 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+type (
+	// Webhook posts every finding as JSON to a URL. If Secret is set, the
+	// body is signed with HMAC-SHA256 and the hex encoded signature is sent
+	// in the SignatureHeader as "sha256=<signature>".
+	Webhook struct {
+		URL      string
+		Secret   string
+		Template *Template
+		Client   *http.Client
+	}
+
+	// Slack posts findings to a Slack-compatible incoming webhook.
+	Slack struct {
+		URL      string
+		Template *Template
+		Client   *http.Client
+	}
+
+	// Teams posts findings to a Microsoft Teams-compatible incoming webhook
+	// as a message card.
+	Teams struct {
+		URL      string
+		Template *Template
+		Client   *http.Client
+	}
+
+	// webhookPayload is the body sent by a Webhook.
+	webhookPayload struct {
+		Subject string   `json:"subject"`
+		Message string   `json:"message"`
+		Finding *Finding `json:"finding"`
+	}
+)
+
+// SignatureHeader is the header holding the HMAC signature of a Webhook body.
+const SignatureHeader = "X-Gopwned-Signature"
+
+// Sign returns the signature of body for secret, as sent in SignatureHeader.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is a valid signature of body for secret.
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// Notify posts f to the webhook.
+func (w *Webhook) Notify(f *Finding) error {
+	subject, message, err := render(w.Template, f)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(webhookPayload{Subject: subject, Message: message, Finding: f})
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	if w.Secret != "" {
+		header.Set(SignatureHeader, Sign(w.Secret, body))
+	}
+	return post(w.Client, w.URL, body, header)
+}
+
+// Notify posts f to the Slack webhook.
+func (s *Slack) Notify(f *Finding) error {
+	subject, message, err := render(s.Template, f)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"text": fmt.Sprintf("*%s*\n%s", subject, message),
+	})
+	if err != nil {
+		return err
+	}
+	return post(s.Client, s.URL, body, nil)
+}
+
+// Notify posts f to the Teams webhook.
+func (t *Teams) Notify(f *Finding) error {
+	subject, message, err := render(t.Template, f)
+	if err != nil {
+		return err
+	}
+
+	card := map[string]interface{}{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"summary":    subject,
+		"title":      subject,
+		"text":       message,
+		"themeColor": "D70000",
+	}
+	if f.Breach != nil && f.Breach.Domain != "" {
+		card["sections"] = []map[string]interface{}{{
+			"facts": []map[string]string{
+				{"name": "Breach", "value": f.Breach.Name},
+				{"name": "Domain", "value": f.Breach.Domain},
+				{"name": "Breach date", "value": f.Breach.BreachDate},
+			},
+		}}
+	}
+
+	body, err := json.Marshal(card)
+	if err != nil {
+		return err
+	}
+	return post(t.Client, t.URL, body, nil)
+}
+
+// post sends body as JSON to url and treats any non-2xx status as an error.
+func post(client *http.Client, url string, body []byte, header http.Header) error {
+	if client == nil {
+		client = DefaultHTTPClient
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// captureServer records the last request body and headers it received.
+func captureServer(t *testing.T, status int) (*httptest.Server, *[]byte, *http.Header) {
+	var body []byte
+	var header http.Header
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		header = r.Header
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, &body, &header
+}
+
+func TestWebhook(t *testing.T) {
+	assert := assert.New(t)
+
+	server, body, header := captureServer(t, http.StatusNoContent)
+	sink := &Webhook{URL: server.URL, Secret: "s3cret"}
+
+	if err := sink.Notify(setupFinding()); err != nil {
+		t.Fatalf("[TestWebhook] returned error: %v", err)
+	}
+
+	assert.Equal("application/json", header.Get("Content-Type"))
+	assert.True(Verify("s3cret", *body, header.Get(SignatureHeader)), "[TestWebhook] Expected a valid HMAC signature.")
+	assert.False(Verify("wrong", *body, header.Get(SignatureHeader)), "[TestWebhook] Expected the signature to depend on the secret.")
+
+	var payload struct {
+		Subject string
+		Finding *Finding
+	}
+	if err := json.Unmarshal(*body, &payload); err != nil {
+		t.Fatalf("[TestWebhook] unable to decode payload: %v", err)
+	}
+	assert.Equal("bar.com found in the Adobe breach", payload.Subject)
+	assert.Equal("adobe.com", payload.Finding.Breach.Domain)
+}
+
+func TestWebhookUnsigned(t *testing.T) {
+	server, _, header := captureServer(t, http.StatusOK)
+
+	if err := (&Webhook{URL: server.URL}).Notify(setupFinding()); err != nil {
+		t.Fatalf("[TestWebhookUnsigned] returned error: %v", err)
+	}
+	assert.Empty(t, header.Get(SignatureHeader), "[TestWebhookUnsigned] Expected no signature without a secret.")
+}
+
+func TestWebhookError(t *testing.T) {
+	server, _, _ := captureServer(t, http.StatusInternalServerError)
+
+	err := (&Webhook{URL: server.URL}).Notify(setupFinding())
+	assert.EqualError(t, err, "webhook responded with 500 Internal Server Error")
+}
+
+func TestSlack(t *testing.T) {
+	assert := assert.New(t)
+
+	server, body, _ := captureServer(t, http.StatusOK)
+
+	if err := (&Slack{URL: server.URL}).Notify(setupFinding()); err != nil {
+		t.Fatalf("[TestSlack] returned error: %v", err)
+	}
+
+	var payload map[string]string
+	if err := json.Unmarshal(*body, &payload); err != nil {
+		t.Fatalf("[TestSlack] unable to decode payload: %v", err)
+	}
+	assert.Contains(payload["text"], "*bar.com found in the Adobe breach*\n")
+	assert.Contains(payload["text"], "Compromised data: Email addresses, Passwords")
+}
+
+func TestTeams(t *testing.T) {
+	assert := assert.New(t)
+
+	server, body, _ := captureServer(t, http.StatusOK)
+
+	if err := (&Teams{URL: server.URL}).Notify(setupFinding()); err != nil {
+		t.Fatalf("[TestTeams] returned error: %v", err)
+	}
+
+	var card struct {
+		Type     string `json:"@type"`
+		Title    string
+		Sections []struct {
+			Facts []struct{ Name, Value string }
+		}
+	}
+	if err := json.Unmarshal(*body, &card); err != nil {
+		t.Fatalf("[TestTeams] unable to decode payload: %v", err)
+	}
+	assert.Equal("MessageCard", card.Type)
+	assert.Equal("bar.com found in the Adobe breach", card.Title)
+	if assert.Len(card.Sections, 1) {
+		assert.Equal("adobe.com", card.Sections[0].Facts[1].Value)
+	}
+}
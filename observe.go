@@ -0,0 +1,264 @@
+package gopwned
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// Logger receives structured log records as a message followed by
+	// alternating keys and values. A *slog.Logger satisfies it.
+	Logger interface {
+		Debug(msg string, args ...interface{})
+		Warn(msg string, args ...interface{})
+	}
+
+	// Metrics receives per-endpoint counters and latencies. Endpoints are
+	// the first path segment of a resource, such as "breachedaccount", or
+	// "range" for the Pwned Passwords API. Status is 0 when the request did
+	// not get a response.
+	Metrics interface {
+		Count(counter Counter, endpoint string, status int)
+		Latency(endpoint string, status int, d time.Duration)
+	}
+
+	// Counter names a metric counted by a Client.
+	Counter string
+
+	// Tracer starts a span around every API call. The returned context is
+	// used for the HTTP request, so transports can propagate the span.
+	Tracer interface {
+		Start(ctx context.Context, name string) (context.Context, Span)
+	}
+
+	// Span is a single traced API call.
+	Span interface {
+		SetAttributes(args ...interface{})
+		SetError(err error)
+		End()
+	}
+
+	// call instruments a single API call with the hooks of a client.
+	call struct {
+		c        *Client
+		endpoint string
+		start    time.Time
+		ctx      context.Context
+		span     Span
+
+		mu       sync.Mutex
+		status   int
+		cacheHit bool
+		retries  int
+		header   http.Header
+		entry    *CacheEntry
+	}
+
+	// callKey is the context key of the call a request belongs to.
+	callKey struct{}
+)
+
+// Counters of a Client.
+const (
+	// CountRequests counts the responses received, per status code.
+	CountRequests Counter = "requests"
+
+	// CountErrors counts the requests that got no response at all.
+	CountErrors Counter = "errors"
+
+	// CountRateLimited counts the responses with status 429.
+	CountRateLimited Counter = "rate_limited"
+
+	// CountCacheHits counts the calls answered from the cache, including
+	// revalidated entries.
+	CountCacheHits Counter = "cache_hits"
+
+	// CountCacheErrors counts the responses that could not be stored in the
+	// range cache.
+	CountCacheErrors Counter = "cache_errors"
+
+	// CountRetries counts the requests sent again for the same call.
+	CountRetries Counter = "retries"
+
+	// CountHedges counts the duplicate requests sent by a Hedger.
+	CountHedges Counter = "hedges"
+
+	// CountCircuitRejected counts the calls failed with ErrCircuitOpen.
+	CountCircuitRejected Counter = "circuit_rejected"
+
+	// CountCircuitOpened counts the times a circuit tripped open.
+	CountCircuitOpened Counter = "circuit_opened"
+)
+
+// redacted replaces the API key and account names wherever they are logged.
+const redacted = "REDACTED"
+
+// accountEndpoints - the endpoints whose path holds the queried account.
+var accountEndpoints = map[string]bool{"breachedaccount": true, "pasteaccount": true}
+
+// begin starts instrumenting a call to endpoint.
+func (c *Client) begin(endpoint string) *call {
+	o := &call{c: c, endpoint: endpoint, start: time.Now(), ctx: context.Background()}
+	if c.Tracer != nil {
+		o.ctx, o.span = c.Tracer.Start(o.ctx, "hibp."+endpoint)
+		o.span.SetAttributes("hibp.endpoint", endpoint)
+	}
+	o.ctx = context.WithValue(o.ctx, callKey{}, o)
+	return o
+}
+
+// retry records that middleware sent the request again after a response
+// with status.
+func (o *call) retry(status int) {
+	o.mu.Lock()
+	o.retries++
+	o.mu.Unlock()
+
+	if m := o.c.Metrics; m != nil {
+		m.Count(CountRetries, o.endpoint, status)
+		if status == http.StatusTooManyRequests {
+			m.Count(CountRateLimited, o.endpoint, status)
+		}
+	}
+}
+
+// hedge records that a Hedger sent a duplicate of the request.
+func (o *call) hedge() {
+	if m := o.c.Metrics; m != nil {
+		m.Count(CountHedges, o.endpoint, 0)
+	}
+}
+
+// rejected records that a Breaker failed the call fast.
+func (o *call) rejected() {
+	if o.span != nil {
+		o.span.SetAttributes("hibp.circuit", StateOpen.String())
+	}
+	if m := o.c.Metrics; m != nil {
+		m.Count(CountCircuitRejected, o.endpoint, 0)
+	}
+}
+
+// transition records that the call moved the circuit of group between
+// states.
+func (o *call) transition(group string, from, to State) {
+	if m := o.c.Metrics; m != nil && to == StateOpen {
+		m.Count(CountCircuitOpened, o.endpoint, o.status)
+	}
+	if l := o.c.Logger; l != nil {
+		l.Warn("gopwned: circuit "+to.String(), "group", group, "from", from.String(), "endpoint", o.endpoint)
+	}
+}
+
+// response records the outcome of sending the request: a response, or the
+// error if none was received.
+func (o *call) response(resp *http.Response, err error) {
+	m := o.c.Metrics
+	if err != nil {
+		if m != nil {
+			m.Count(CountErrors, o.endpoint, 0)
+		}
+		return
+	}
+
+	o.status = resp.StatusCode
+	o.header = resp.Header
+	if m != nil {
+		m.Count(CountRequests, o.endpoint, o.status)
+		if o.status == http.StatusTooManyRequests {
+			m.Count(CountRateLimited, o.endpoint, o.status)
+		}
+		m.Latency(o.endpoint, o.status, time.Since(o.start))
+	}
+}
+
+// hit records that the call was answered from entry of the cache.
+func (o *call) hit(entry *CacheEntry) {
+	o.cacheHit = true
+	o.entry = entry
+	if o.status == 0 {
+		o.status = http.StatusOK
+	}
+	if o.c.Metrics != nil {
+		o.c.Metrics.Count(CountCacheHits, o.endpoint, o.status)
+	}
+}
+
+// cacheFailed records that the response could not be stored in the cache.
+func (o *call) cacheFailed(err error) {
+	if m := o.c.Metrics; m != nil {
+		m.Count(CountCacheErrors, o.endpoint, o.status)
+	}
+	if l := o.c.Logger; l != nil {
+		l.Warn("gopwned: cache write failed", "endpoint", o.endpoint, "error", err.Error())
+	}
+}
+
+// end finishes the call. req may be nil if the call failed before a request
+// was built.
+func (o *call) end(req *http.Request, err error) {
+	o.mu.Lock()
+	retries := o.retries
+	o.mu.Unlock()
+
+	if o.c.response != nil {
+		o.fill(o.c.response, retries)
+	}
+
+	if o.span != nil {
+		o.span.SetAttributes("http.status_code", o.status, "hibp.cache_hit", o.cacheHit, "hibp.retries", retries)
+		if err != nil {
+			o.span.SetError(err)
+		}
+		o.span.End()
+	}
+
+	if o.c.Logger == nil {
+		return
+	}
+	args := []interface{}{
+		"endpoint", o.endpoint,
+		"status", o.status,
+		"cache_hit", o.cacheHit,
+		"retries", retries,
+		"duration", time.Since(o.start),
+	}
+	if req != nil {
+		args = append(args, "method", req.Method, "url", redactURL(req.URL, o.endpoint), "header", redactHeader(req.Header))
+	}
+	if retry := o.header.Get("Retry-After"); retry != "" {
+		args = append(args, "retry_after", retry)
+	}
+	if err != nil {
+		o.c.Logger.Warn("gopwned: request failed", append(args, "error", err.Error())...)
+		return
+	}
+	o.c.Logger.Debug("gopwned: request", args...)
+}
+
+// redactURL returns u as a string, with the account replaced for endpoints
+// that take one in their path.
+func redactURL(u *url.URL, endpoint string) string {
+	if !accountEndpoints[endpoint] {
+		return u.String()
+	}
+	clean := *u
+	segment := "/" + endpoint + "/"
+	if i := strings.Index(clean.Path, segment); i >= 0 {
+		clean.Path, clean.RawPath = clean.Path[:i+len(segment)]+redacted, ""
+	}
+	return clean.String()
+}
+
+// redactHeader returns a copy of header without the value of the API key.
+func redactHeader(header http.Header) http.Header {
+	clean := header.Clone()
+	if clean.Get("hibp-api-key") != "" {
+		clean.Set("hibp-api-key", redacted)
+	}
+	return clean
+}
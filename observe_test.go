@@ -0,0 +1,161 @@
+package gopwned
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mavjs/goPwned/hibptest"
+)
+
+type (
+	testLogger struct {
+		mu      sync.Mutex
+		records []string
+	}
+
+	testMetrics struct {
+		mu        sync.Mutex
+		counts    map[string]int
+		latencies int
+	}
+
+	testTracer struct {
+		spans []*testSpan
+	}
+
+	testSpan struct {
+		name  string
+		attrs map[string]interface{}
+		err   error
+		ended bool
+	}
+)
+
+func (l *testLogger) log(level, msg string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records = append(l.records, fmt.Sprint(level, " ", msg, " ", args))
+}
+
+func (l *testLogger) Debug(msg string, args ...interface{}) { l.log("DEBUG", msg, args...) }
+func (l *testLogger) Warn(msg string, args ...interface{})  { l.log("WARN", msg, args...) }
+
+func (m *testMetrics) Count(counter Counter, endpoint string, status int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counts[fmt.Sprintf("%s %s %d", counter, endpoint, status)]++
+}
+
+func (m *testMetrics) Latency(endpoint string, status int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.latencies++
+}
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &testSpan{name: name, attrs: make(map[string]interface{})}
+	t.spans = append(t.spans, span)
+	return ctx, span
+}
+
+func (s *testSpan) SetAttributes(args ...interface{}) {
+	for i := 0; i+1 < len(args); i += 2 {
+		s.attrs[args[i].(string)] = args[i+1]
+	}
+}
+
+func (s *testSpan) SetError(err error) { s.err = err }
+func (s *testSpan) End()               { s.ended = true }
+
+func setupObservedClient(t *testing.T) (*hibptest.Server, *Client, *testLogger, *testMetrics, *testTracer) {
+	srv := hibptest.NewServer()
+	t.Cleanup(srv.Close)
+
+	logger := &testLogger{}
+	metrics := &testMetrics{counts: make(map[string]int)}
+	tracer := &testTracer{}
+
+	client := NewClient(nil, hibptest.APIKey)
+	client.BaseURL, _ = url.Parse(srv.BaseURL())
+	client.PwnPwdURL, _ = url.Parse(srv.RangeURL())
+	client.Logger = logger
+	client.Metrics = metrics
+	client.Tracer = tracer
+	return srv, client, logger, metrics, tracer
+}
+
+func TestObserveRequests(t *testing.T) {
+	assert := assert.New(t)
+
+	srv, client, logger, metrics, tracer := setupObservedClient(t)
+	client.Cache = NewMemoryCache(10)
+
+	client.GetAccountBreaches("account-exists@"+hibptest.Domain, "", true, false)
+	client.GetBreachedSites("")
+	client.GetBreachedSites("")
+	client.GetPwnedPasswords("21BD1", false)
+
+	srv.Fail("/api/v3/pasteaccount/", hibptest.Fault{Status: http.StatusTooManyRequests, RetryAfter: 3, Count: 1})
+	_, err := client.GetAccountPastes("account-exists@" + hibptest.Domain)
+	assert.Error(err)
+
+	assert.Equal(map[string]int{
+		"requests breachedaccount 200":  1,
+		"requests breaches 200":         1,
+		"cache_hits breaches 200":       1,
+		"requests range 200":            1,
+		"requests pasteaccount 429":     1,
+		"rate_limited pasteaccount 429": 1,
+	}, metrics.counts, "[TestObserveRequests] Unexpected counters.")
+	assert.Equal(4, metrics.latencies, "[TestObserveRequests] Expected a latency for every response received.")
+
+	if assert.Len(tracer.spans, 5) {
+		assert.Equal("hibp.breachedaccount", tracer.spans[0].name)
+		assert.Equal(200, tracer.spans[0].attrs["http.status_code"])
+		assert.Equal(true, tracer.spans[2].attrs["hibp.cache_hit"], "[TestObserveRequests] Expected the cache hit on the span.")
+		assert.Equal("hibp.range", tracer.spans[3].name)
+		assert.Equal(err, tracer.spans[4].err)
+		for _, span := range tracer.spans {
+			assert.True(span.ended, "[TestObserveRequests] Expected every span to end.")
+		}
+	}
+
+	if assert.Len(logger.records, 5) {
+		assert.True(strings.HasPrefix(logger.records[4], "WARN"), "[TestObserveRequests] Expected failures to be logged as warnings.")
+		assert.Contains(logger.records[4], "retry_after 3")
+	}
+	for _, record := range logger.records {
+		assert.NotContains(record, hibptest.APIKey, "[TestObserveRequests] Expected the API key to be redacted.")
+		assert.NotContains(record, "account-exists", "[TestObserveRequests] Expected the account to be redacted.")
+	}
+	assert.Contains(logger.records[0], redacted)
+	assert.Contains(logger.records[0], "/breachedaccount/"+redacted+"?", "[TestObserveRequests] Expected the query of account requests to be kept.")
+}
+
+func TestObserveErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	srv, client, logger, metrics, tracer := setupObservedClient(t)
+	srv.Close()
+
+	_, err := client.GetBreachedSites("")
+	assert.Error(err)
+
+	client.Token = ""
+	_, err = client.GetAccountPastes("account-exists@" + hibptest.Domain)
+	assert.Error(err)
+
+	assert.Equal(map[string]int{"errors breaches 0": 1}, metrics.counts, "[TestObserveErrors] Expected only requests that were sent to count as errors.")
+	assert.Len(logger.records, 2)
+	if assert.Len(tracer.spans, 2) {
+		assert.NotNil(tracer.spans[1].err)
+	}
+}
@@ -0,0 +1,38 @@
+package password
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+)
+
+// Corpus is a Ranger serving ranges from a local copy of the Pwned Passwords
+// corpus: a file system holding a "<PREFIX>.txt" file per hash prefix, as
+// written by the Pwned Passwords downloader. It can be a directory, through
+// os.DirFS, or a subset embedded in the binary:
+//
+//	//go:embed corpus
+//	var embedded embed.FS
+//
+//	corpus, _ := fs.Sub(embedded, "corpus")
+//	v.Local = password.Corpus{FS: corpus}
+//
+// Prefixes without a file fail with ErrNotInCorpus, so a subset of the
+// corpus does not accept passwords it knows nothing about: a Validator
+// applies its Policy to them instead.
+type Corpus struct {
+	FS fs.FS
+}
+
+// ErrNotInCorpus is returned for a prefix the corpus holds no range for.
+var ErrNotInCorpus = errors.New("hash prefix not in the local corpus")
+
+// GetPwnedPasswords returns the range of chars. Padding is ignored, since no
+// request leaves the host.
+func (c Corpus) GetPwnedPasswords(chars string, addPadding bool) ([]byte, error) {
+	body, err := fs.ReadFile(c.FS, strings.ToUpper(chars)+".txt")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotInCorpus
+	}
+	return body, err
+}
@@ -0,0 +1,73 @@
+package password
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCorpus(t *testing.T) {
+	assert := assert.New(t)
+	corpus := Corpus{FS: os.DirFS("testdata/corpus")}
+
+	count, err := Count(corpus, "P@ssw0rd")
+	assert.NoError(err)
+	assert.Equal(int64(83129), count, "[TestCorpus] Expected the count of the range file.")
+
+	_, err = Count(corpus, "correct horse battery staple tango")
+	assert.True(errors.Is(err, ErrNotInCorpus), "[TestCorpus] Expected prefixes without a file to fail, got %v", err)
+
+	_, err = Count(Corpus{FS: fstest.MapFS{"21BD1.txt": {Data: []byte("not a range")}}}, "P@ssw0rd")
+	assert.Error(err, "[TestCorpus] Expected malformed range files to fail.")
+}
+
+func TestValidatorTiers(t *testing.T) {
+	assert := assert.New(t)
+
+	var logs bytes.Buffer
+	online := &fakeRanger{}
+	v := NewValidator(online, 0, FailClosed)
+	v.Local = Corpus{FS: os.DirFS("testdata/corpus")}
+	v.Logger = log.New(&logs, "", 0)
+
+	result := v.Check("P@ssw0rd")
+	assert.Equal(TierOnline, result.Tier, "[TestValidatorTiers] Expected the range API to answer.")
+	assert.Equal(Pwned, result.Outcome)
+
+	online.err = errors.New("circuit open")
+	result = v.Check("P@ssw0rd")
+	assert.Equal(TierLocal, result.Tier, "[TestValidatorTiers] Expected the local copy to answer when the API fails.")
+	assert.Equal(Pwned, result.Outcome)
+	assert.Equal(int64(83129), result.Count)
+	assert.NoError(result.Err)
+
+	v.Local = Corpus{FS: fstest.MapFS{"21BD1.txt": {Data: []byte("not a range")}}}
+	result = v.Check("P@ssw0rd")
+	assert.Equal(TierPolicy, result.Tier, "[TestValidatorTiers] Expected the policy to decide when both fail.")
+	assert.Equal(Unavailable, result.Outcome)
+	assert.False(result.Allowed(v.Policy))
+
+	assert.Contains(logs.String(), `falling back to local copy: "circuit open"`)
+	assert.Contains(logs.String(), "outcome=pwned count=83129 tier=local")
+	assert.NotContains(logs.String(), "P@ssw0rd", "[TestValidatorTiers] The password must never be logged.")
+}
+
+func TestValidatorPartialCorpus(t *testing.T) {
+	assert := assert.New(t)
+
+	v := NewValidator(&fakeRanger{err: errors.New("unreachable")}, 0, FailClosed)
+	v.Local = Corpus{FS: os.DirFS("testdata/corpus")}
+
+	result := v.Check("correct horse battery staple tango")
+	assert.Equal(TierPolicy, result.Tier, "[TestValidatorPartialCorpus] Expected the policy to decide for prefixes missing from the corpus.")
+	assert.Equal(Unavailable, result.Outcome)
+	assert.Error(v.Validate("correct horse battery staple tango"), "[TestValidatorPartialCorpus] Expected fail closed to reject.")
+
+	v.Policy = FailOpen
+	assert.NoError(v.Validate("correct horse battery staple tango"), "[TestValidatorPartialCorpus] Expected fail open to accept.")
+}
@@ -0,0 +1,67 @@
+// Package password checks passwords against the Pwned Passwords data set
+// using the k-anonymity range API, so only the first 5 characters of the
+// SHA-1 hash ever leave the host. It provides a standalone Validator and an
+// http.Handler middleware for sign-up and password change flows.
+package password
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Ranger is implemented by anything that can search the Pwned Passwords
+// range API, such as `*gopwned.Client`.
+type Ranger interface {
+	GetPwnedPasswords(chars string, addPadding bool) ([]byte, error)
+}
+
+// Hash returns the upper case hex encoded SHA-1 hash of password.
+func Hash(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// Count returns how many times password has been seen in the Pwned
+// Passwords data set, 0 if it has never been seen. The request is padded, so
+// the response size does not leak whether the password was found.
+func Count(r Ranger, password string) (int64, error) {
+	hash := Hash(password)
+
+	body, err := r.GetPwnedPasswords(hash[:5], true)
+	if err != nil {
+		return 0, err
+	}
+	return Lookup(body, hash[5:])
+}
+
+// Lookup finds suffix in a range response and returns its count. Padding
+// entries have a count of 0, so they are reported as not found.
+func Lookup(body []byte, suffix string) (int64, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		i := strings.IndexByte(line, ':')
+		if i < 0 {
+			return 0, fmt.Errorf("malformed range line %q", line)
+		}
+		if !strings.EqualFold(line[:i], suffix) {
+			continue
+		}
+
+		count, err := strconv.ParseInt(line[i+1:], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("malformed count in range line %q", line)
+		}
+		return count, nil
+	}
+	return 0, scanner.Err()
+}
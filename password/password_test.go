@@ -0,0 +1,76 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRanger serves a single range holding the hash of "P@ssw0rd".
+type fakeRanger struct {
+	err      error
+	prefixes []string
+	padded   bool
+}
+
+func (f *fakeRanger) GetPwnedPasswords(chars string, addPadding bool) ([]byte, error) {
+	f.prefixes = append(f.prefixes, chars)
+	f.padded = addPadding
+	if f.err != nil {
+		return nil, f.err
+	}
+	if chars != "21BD1" {
+		return []byte("0018A45C4D1DEF81644B54AB7F969B88D65:0\r\n"), nil
+	}
+	return []byte("0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n" +
+		"2DC183F740EE76F27B78EB39C8AD972A757:83129\r\n" +
+		"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF:0"), nil
+}
+
+func TestHash(t *testing.T) {
+	assert.Equal(t, "21BD12DC183F740EE76F27B78EB39C8AD972A757", Hash("P@ssw0rd"))
+}
+
+func TestCount(t *testing.T) {
+	assert := assert.New(t)
+
+	ranger := &fakeRanger{}
+
+	count, err := Count(ranger, "P@ssw0rd")
+	if err != nil {
+		t.Fatalf("[TestCount] returned error: %v", err)
+	}
+	assert.Equal(int64(83129), count)
+	assert.Equal([]string{"21BD1"}, ranger.prefixes, "[TestCount] Expected only the hash prefix to be sent.")
+	assert.True(ranger.padded, "[TestCount] Expected the request to be padded.")
+
+	count, err = Count(ranger, "correct horse battery staple tango")
+	if err != nil {
+		t.Fatalf("[TestCount] returned error: %v", err)
+	}
+	assert.Equal(int64(0), count)
+
+	_, err = Count(&fakeRanger{err: errors.New("unreachable")}, "P@ssw0rd")
+	assert.EqualError(err, "unreachable")
+}
+
+func TestLookup(t *testing.T) {
+	assert := assert.New(t)
+
+	body := []byte("AAA:3\r\nbbb:5\r\n\r\n")
+
+	for suffix, want := range map[string]int64{"AAA": 3, "BBB": 5, "CCC": 0} {
+		got, err := Lookup(body, suffix)
+		if err != nil {
+			t.Fatalf("[TestLookup] returned error: %v", err)
+		}
+		assert.Equal(want, got, fmt.Sprintf("[TestLookup] Unexpected count for %s.", suffix))
+	}
+
+	_, err := Lookup([]byte("AAA:three"), "AAA")
+	assert.Error(err)
+	_, err = Lookup([]byte("garbage"), "AAA")
+	assert.Error(err)
+}
@@ -0,0 +1,264 @@
+package password
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+type (
+	// Policy decides what happens to a password when the range API cannot be
+	// reached.
+	Policy int
+
+	// Outcome is the result of a password check.
+	Outcome string
+
+	// Tier names what answered a password check.
+	Tier string
+
+	// Result describes a password check. It never holds the password.
+	Result struct {
+		Outcome Outcome
+		// Tier is the source of the outcome, to audit how often checks
+		// degrade to the local copy or the policy.
+		Tier Tier
+		// Count is how many times the password was seen, if known.
+		Count int64
+		// Err is the lookup error for an unavailable outcome.
+		Err error
+	}
+
+	// Validator checks passwords against the range API, then against Local
+	// if the API fails, e.g. with gopwned.ErrCircuitOpen, and then applies
+	// Policy if both fail.
+	Validator struct {
+		Ranger Ranger
+		// Local, if set, is used when Ranger fails, e.g. a Corpus.
+		Local Ranger
+		// Threshold is the highest count that is still accepted, 0 rejects
+		// every password that has been seen at all.
+		Threshold int64
+		// Policy applies when the range API cannot be reached.
+		Policy Policy
+		// Logger, if set, receives one line per check with its outcome and
+		// count. The password and its hash are never logged.
+		Logger *log.Logger
+	}
+
+	// Extractor pulls the password out of a request. It returns false if the
+	// request does not carry a password.
+	Extractor func(r *http.Request) (string, bool, error)
+
+	// errorResponse is the body of a rejected request.
+	errorResponse struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+		Count   int64  `json:"count,omitempty"`
+	}
+)
+
+// Policies for an unreachable range API.
+const (
+	// FailOpen accepts the password.
+	FailOpen Policy = iota
+	// FailClosed rejects the password.
+	FailClosed
+)
+
+// Outcomes of a password check.
+const (
+	Accepted    Outcome = "accepted"
+	Pwned       Outcome = "pwned"
+	Unavailable Outcome = "unavailable"
+)
+
+// Tiers of a password check.
+const (
+	// TierOnline is the range API.
+	TierOnline Tier = "online"
+	// TierLocal is the Local ranger of the validator.
+	TierLocal Tier = "local"
+	// TierPolicy is the policy applied when no ranger answered.
+	TierPolicy Tier = "policy"
+)
+
+// maxBodySize - the largest JSON body the middleware reads.
+const maxBodySize = 1 << 20
+
+// Allowed reports whether the password may be used.
+func (r *Result) Allowed(p Policy) bool {
+	switch r.Outcome {
+	case Accepted:
+		return true
+	case Unavailable:
+		return p == FailOpen
+	default:
+		return false
+	}
+}
+
+// NewValidator creates a validator rejecting passwords seen more than
+// threshold times, with the given policy for when the API is unreachable.
+func NewValidator(r Ranger, threshold int64, policy Policy) *Validator {
+	return &Validator{Ranger: r, Threshold: threshold, Policy: policy}
+}
+
+// Check looks up password and compares its count with the threshold.
+func (v *Validator) Check(password string) *Result {
+	result := &Result{Outcome: Accepted, Tier: TierOnline}
+
+	count, err := Count(v.Ranger, password)
+	if err != nil && v.Local != nil {
+		v.fallback(err)
+		result.Tier = TierLocal
+		count, err = Count(v.Local, password)
+	}
+
+	switch {
+	case err != nil:
+		result.Outcome = Unavailable
+		result.Tier = TierPolicy
+		result.Err = err
+	case count > v.Threshold:
+		result.Outcome = Pwned
+		result.Count = count
+	default:
+		result.Count = count
+	}
+
+	v.log(result)
+	return result
+}
+
+// Validate returns nil if password may be used under the validator's policy.
+func (v *Validator) Validate(password string) error {
+	result := v.Check(password)
+	if result.Allowed(v.Policy) {
+		return nil
+	}
+	if result.Outcome == Unavailable {
+		return result.Err
+	}
+	return errors.New("the password has appeared in a data breach")
+}
+
+func (v *Validator) log(r *Result) {
+	if v.Logger == nil {
+		return
+	}
+	if r.Err != nil {
+		v.Logger.Printf("password check: outcome=%s error=%q tier=%s", r.Outcome, r.Err, r.Tier)
+		return
+	}
+	v.Logger.Printf("password check: outcome=%s count=%d tier=%s", r.Outcome, r.Count, r.Tier)
+}
+
+// fallback logs why the range API was not used.
+func (v *Validator) fallback(err error) {
+	if v.Logger != nil {
+		v.Logger.Printf("password check: falling back to local copy: %q", err)
+	}
+}
+
+// Middleware checks the password extracted by extract before calling next.
+// Pwned passwords are rejected with a 422 JSON response, and if the API is
+// unreachable under the FailClosed policy the request is rejected with 503.
+// Requests without a password are passed through.
+func (v *Validator) Middleware(extract Extractor, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		password, ok, err := extract(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, &errorResponse{Error: "invalid_request", Message: "the request body could not be read"})
+			return
+		}
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		result := v.Check(password)
+		switch {
+		case result.Allowed(v.Policy):
+			next.ServeHTTP(w, r)
+		case result.Outcome == Pwned:
+			writeError(w, http.StatusUnprocessableEntity, &errorResponse{
+				Error:   "password_pwned",
+				Message: "this password has appeared in a data breach and must not be used",
+				Count:   result.Count,
+			})
+		default:
+			writeError(w, http.StatusServiceUnavailable, &errorResponse{
+				Error:   "password_check_unavailable",
+				Message: "the password could not be checked, please try again later",
+			})
+		}
+	})
+}
+
+func writeError(w http.ResponseWriter, status int, body *errorResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// FormField extracts the password from a form field of a urlencoded or
+// multipart request body.
+func FormField(name string) Extractor {
+	return func(r *http.Request) (string, bool, error) {
+		if err := r.ParseMultipartForm(maxBodySize); err != nil && err != http.ErrNotMultipart {
+			return "", false, err
+		}
+		if _, ok := r.PostForm[name]; !ok {
+			return "", false, nil
+		}
+		return r.PostForm.Get(name), true, nil
+	}
+}
+
+// JSONField extracts the password from a JSON body, following a dotted path
+// of object keys such as "user.password". A field that is present but not a
+// string is an error, so it cannot slip past the check. The body is restored,
+// so the next handler can decode it again.
+func JSONField(path string) Extractor {
+	keys := strings.Split(path, ".")
+
+	return func(r *http.Request) (string, bool, error) {
+		if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt != "application/json" || r.Body == nil {
+			return "", false, nil
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, maxBodySize))
+		r.Body.Close()
+		if err != nil {
+			return "", false, err
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		var value interface{}
+		if err := json.Unmarshal(body, &value); err != nil {
+			return "", false, err
+		}
+		for _, key := range keys {
+			obj, ok := value.(map[string]interface{})
+			if !ok {
+				return "", false, nil
+			}
+			if value, ok = obj[key]; !ok {
+				return "", false, nil
+			}
+		}
+
+		password, ok := value.(string)
+		if !ok {
+			return "", false, fmt.Errorf("field %s is not a string", path)
+		}
+		return password, true, nil
+	}
+}
@@ -0,0 +1,143 @@
+package password
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidator(t *testing.T) {
+	assert := assert.New(t)
+
+	var logs bytes.Buffer
+	v := NewValidator(&fakeRanger{}, 0, FailClosed)
+	v.Logger = log.New(&logs, "", 0)
+
+	result := v.Check("P@ssw0rd")
+	assert.Equal(Pwned, result.Outcome)
+	assert.Equal(int64(83129), result.Count)
+	assert.EqualError(v.Validate("P@ssw0rd"), "the password has appeared in a data breach")
+
+	assert.NoError(v.Validate("correct horse battery staple tango"))
+
+	v.Threshold = 100000
+	assert.NoError(v.Validate("P@ssw0rd"), "[TestValidator] Expected counts below the threshold to be accepted.")
+
+	assert.Contains(logs.String(), "outcome=pwned count=83129")
+	assert.NotContains(logs.String(), "P@ssw0rd", "[TestValidator] The password must never be logged.")
+	assert.NotContains(logs.String(), "21BD1", "[TestValidator] The password hash must never be logged.")
+}
+
+func TestValidatorPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	ranger := &fakeRanger{err: errors.New("unreachable")}
+
+	assert.NoError(NewValidator(ranger, 0, FailOpen).Validate("P@ssw0rd"), "[TestValidatorPolicy] Expected fail open to accept.")
+	assert.EqualError(NewValidator(ranger, 0, FailClosed).Validate("P@ssw0rd"), "unreachable", "[TestValidatorPolicy] Expected fail closed to reject.")
+}
+
+func setupMiddleware(v *Validator, extract Extractor) (http.Handler, *int) {
+	calls := 0
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		// The next handler must still be able to read the body.
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	})
+	return v.Middleware(extract, next), &calls
+}
+
+func TestMiddlewareForm(t *testing.T) {
+	assert := assert.New(t)
+
+	handler, calls := setupMiddleware(NewValidator(&fakeRanger{}, 0, FailOpen), FormField("password"))
+
+	form := url.Values{"username": {"foo"}, "password": {"P@ssw0rd"}}
+	req := httptest.NewRequest("POST", "/register", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal("application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(`{"error":"password_pwned","message":"this password has appeared in a data breach and must not be used","count":83129}`, rec.Body.String())
+	assert.Equal(0, *calls, "[TestMiddlewareForm] Expected the request to be rejected.")
+
+	form.Set("password", "correct horse battery staple tango")
+	req = httptest.NewRequest("POST", "/register", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Equal(1, *calls)
+}
+
+func TestMiddlewareJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	handler, calls := setupMiddleware(NewValidator(&fakeRanger{}, 0, FailOpen), JSONField("user.password"))
+
+	body := `{"user":{"name":"foo","password":"correct horse battery staple tango"}}`
+	req := httptest.NewRequest("POST", "/register", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Equal(body, rec.Body.String(), "[TestMiddlewareJSON] Expected the body to be restored for the next handler.")
+
+	req = httptest.NewRequest("POST", "/register", strings.NewReader(`{"user":{"password":"P@ssw0rd"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(http.StatusUnprocessableEntity, rec.Code)
+
+	// No password in the body, nothing to check.
+	req = httptest.NewRequest("POST", "/profile", strings.NewReader(`{"user":{"name":"foo"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(http.StatusOK, rec.Code)
+
+	req = httptest.NewRequest("POST", "/register", strings.NewReader(`{"user":`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(http.StatusBadRequest, rec.Code)
+
+	// A password that is not a string must not skip the check.
+	for _, body := range []string{`{"user":{"password":123}}`, `{"user":{"password":["x"]}}`, `{"user":{"password":null}}`} {
+		req = httptest.NewRequest("POST", "/register", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(http.StatusBadRequest, rec.Code, "[TestMiddlewareJSON] Expected %s to be rejected.", body)
+	}
+
+	assert.Equal(2, *calls)
+}
+
+func TestMiddlewareFailClosed(t *testing.T) {
+	assert := assert.New(t)
+
+	handler, calls := setupMiddleware(NewValidator(&fakeRanger{err: errors.New("unreachable")}, 0, FailClosed), JSONField("password"))
+
+	req := httptest.NewRequest("POST", "/register", strings.NewReader(`{"password":"correct horse battery staple tango"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(rec.Body.String(), `"error":"password_check_unavailable"`)
+	assert.Equal(0, *calls)
+}
@@ -0,0 +1,254 @@
+// Package policy evaluates passwords against NIST SP 800-63B style rules:
+// length limits, context-specific words, repetitive and sequential
+// characters, a local deny-list and the Pwned Passwords data set. The result
+// is a Verdict made of machine readable reasons, so user interfaces can
+// localize the messages themselves.
+package policy
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mavjs/goPwned/password"
+)
+
+type (
+	// Severity tells whether a reason rejects the password or is only a
+	// warning.
+	Severity string
+
+	// Code identifies a rule that was not met.
+	Code string
+
+	// Reason is a single finding about a password. Params holds the values
+	// needed to build a localized message, such as the minimum length.
+	Reason struct {
+		Code     Code                   `json:"code"`
+		Severity Severity               `json:"severity"`
+		Params   map[string]interface{} `json:"params,omitempty"`
+	}
+
+	// Verdict is the outcome of evaluating a password.
+	Verdict struct {
+		Allowed bool     `json:"allowed"`
+		Reasons []Reason `json:"reasons,omitempty"`
+	}
+
+	// Policy holds the rules a password is evaluated against. The zero value
+	// only enforces the NIST defaults for length, repetition and sequences.
+	Policy struct {
+		// MinLength and MaxLength bound the number of characters (Unicode
+		// code points). They default to 8 and 64.
+		MinLength int
+		MaxLength int
+
+		// MaxRepeat is the longest allowed run of the same character, and
+		// MaxSequence the longest allowed run of consecutive characters such
+		// as "abcd" or "4321". Both default to 3.
+		MaxRepeat   int
+		MaxSequence int
+
+		// ContextWords are rejected as part of any password, e.g. the name of
+		// the service. Words shorter than 3 characters are ignored.
+		ContextWords []string
+
+		// Ranger, if set, is used to look the password up in the Pwned
+		// Passwords data set. Passwords seen more than PwnedThreshold times
+		// are rejected, and if the lookup fails the password is rejected
+		// only under the FailClosed policy.
+		Ranger         password.Ranger
+		PwnedThreshold int64
+		Unavailable    password.Policy
+
+		denyList map[string]bool
+	}
+)
+
+// Severities of a reason.
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Codes of the rules checked by a Policy.
+const (
+	TooShort         Code = "too_short"
+	TooLong          Code = "too_long"
+	ContextWord      Code = "context_word"
+	Repetitive       Code = "repetitive"
+	Sequential       Code = "sequential"
+	DenyListed       Code = "deny_listed"
+	Pwned            Code = "pwned"
+	PwnedCheckFailed Code = "pwned_check_failed"
+)
+
+// minContextWordLen - context words shorter than this are ignored.
+const minContextWordLen = 3
+
+// DenyList adds words to the local deny-list. Matching is case insensitive
+// and on the whole password.
+func (p *Policy) DenyList(words ...string) {
+	if p.denyList == nil {
+		p.denyList = make(map[string]bool, len(words))
+	}
+	for _, word := range words {
+		if word = strings.TrimSpace(word); word != "" {
+			p.denyList[strings.ToLower(word)] = true
+		}
+	}
+}
+
+// LoadDenyList adds every non-empty line read from r to the deny-list. Lines
+// starting with "#" are ignored.
+func (p *Policy) LoadDenyList(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.DenyList(line)
+	}
+	return scanner.Err()
+}
+
+// Evaluate checks pw against every rule. Context holds words specific to the
+// user, such as the username or the email address, which must not be part of
+// the password.
+func (p *Policy) Evaluate(pw string, context ...string) *Verdict {
+	v := &Verdict{}
+
+	length := utf8.RuneCountInString(pw)
+	if min := withDefault(p.MinLength, 8); length < min {
+		v.add(TooShort, SeverityError, map[string]interface{}{"min": min, "length": length})
+	}
+	if max := withDefault(p.MaxLength, 64); length > max {
+		v.add(TooLong, SeverityError, map[string]interface{}{"max": max, "length": length})
+	}
+
+	lower := strings.ToLower(pw)
+	words := append(append([]string{}, context...), p.ContextWords...)
+	for _, word := range contextWords(words) {
+		if strings.Contains(lower, word) {
+			v.add(ContextWord, SeverityError, map[string]interface{}{"word": word})
+		}
+	}
+
+	runes := []rune(lower)
+	if max := withDefault(p.MaxRepeat, 3); longestRun(runes, 0) > max {
+		v.add(Repetitive, SeverityError, map[string]interface{}{"max": max})
+	}
+	if max := withDefault(p.MaxSequence, 3); longestRun(runes, 1) > max || longestRun(runes, -1) > max {
+		v.add(Sequential, SeverityError, map[string]interface{}{"max": max})
+	}
+
+	if p.denyList[lower] {
+		v.add(DenyListed, SeverityError, nil)
+	}
+
+	if p.Ranger != nil {
+		p.checkPwned(v, pw)
+	}
+
+	v.Allowed = true
+	for _, r := range v.Reasons {
+		if r.Severity == SeverityError {
+			v.Allowed = false
+		}
+	}
+	return v
+}
+
+// checkPwned adds a reason if pw appears in the Pwned Passwords data set. A
+// count at or below the threshold is still reported as a warning.
+func (p *Policy) checkPwned(v *Verdict, pw string) {
+	count, err := password.Count(p.Ranger, pw)
+	switch {
+	case err != nil:
+		severity := SeverityWarning
+		if p.Unavailable == password.FailClosed {
+			severity = SeverityError
+		}
+		v.add(PwnedCheckFailed, severity, nil)
+	case count > p.PwnedThreshold:
+		v.add(Pwned, SeverityError, map[string]interface{}{"count": count})
+	case count > 0:
+		v.add(Pwned, SeverityWarning, map[string]interface{}{"count": count})
+	}
+}
+
+func (v *Verdict) add(code Code, severity Severity, params map[string]interface{}) {
+	v.Reasons = append(v.Reasons, Reason{Code: code, Severity: severity, Params: params})
+}
+
+// Has reports whether the verdict holds a reason with code.
+func (v *Verdict) Has(code Code) bool {
+	for _, r := range v.Reasons {
+		if r.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// contextWords lower cases words and splits them on anything that is not a
+// letter or digit, so "foo.bar@example.com" yields "foo", "bar" and
+// "example". The top-level domain of an email address is dropped, as are
+// parts that are too short to be meaningful.
+func contextWords(words []string) []string {
+	var parts []string
+	seen := make(map[string]bool)
+	for _, word := range words {
+		word = strings.ToLower(word)
+		if at := strings.LastIndex(word, "@"); at >= 0 {
+			if dot := strings.LastIndex(word, "."); dot > at {
+				word = word[:dot]
+			}
+		}
+
+		for _, part := range strings.FieldsFunc(word, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			if utf8.RuneCountInString(part) >= minContextWordLen && !seen[part] {
+				seen[part] = true
+				parts = append(parts, part)
+			}
+		}
+	}
+	return parts
+}
+
+// longestRun returns the length of the longest run of runes where each rune
+// is the previous one plus step. A step of 0 finds repeated characters.
+func longestRun(runes []rune, step rune) int {
+	if len(runes) == 0 {
+		return 0
+	}
+
+	longest, run := 1, 1
+	for i := 1; i < len(runes); i++ {
+		if runes[i] == runes[i-1]+step && (step == 0 || isAlnum(runes[i]) && isAlnum(runes[i-1])) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > longest {
+			longest = run
+		}
+	}
+	return longest
+}
+
+func isAlnum(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func withDefault(value, def int) int {
+	if value <= 0 {
+		return def
+	}
+	return value
+}
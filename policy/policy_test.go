@@ -0,0 +1,116 @@
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mavjs/goPwned/password"
+)
+
+// fakeRanger knows a single password, "P@ssw0rd", seen 83129 times.
+type fakeRanger struct {
+	err error
+}
+
+func (f *fakeRanger) GetPwnedPasswords(chars string, addPadding bool) ([]byte, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return []byte("2DC183F740EE76F27B78EB39C8AD972A757:83129\r\n"), nil
+}
+
+func codes(v *Verdict) []Code {
+	var c []Code
+	for _, r := range v.Reasons {
+		c = append(c, r.Code)
+	}
+	return c
+}
+
+func TestEvaluate(t *testing.T) {
+	assert := assert.New(t)
+
+	p := &Policy{ContextWords: []string{"gopwned"}}
+	p.DenyList("Tr0ub4dor&3")
+
+	tests := []struct {
+		password string
+		context  []string
+		want     []Code
+	}{
+		{"correct horse battery staple", nil, nil},
+		{"short", nil, []Code{TooShort}},
+		{strings.Repeat("xy", 33), nil, []Code{TooLong}},
+		{"my gopwned secret", nil, []Code{ContextWord}},
+		{"hello alice, how are you", []string{"alice@example.com"}, []Code{ContextWord}},
+		{"welcome to the community", []string{"alice@example.com"}, nil},
+		{"passwordzzzz!", nil, []Code{Repetitive}},
+		{"letmein12345", nil, []Code{Sequential}},
+		{"zyxw is backwards", nil, []Code{Sequential}},
+		{"tr0ub4dor&3", nil, []Code{DenyListed}},
+	}
+
+	for _, tt := range tests {
+		v := p.Evaluate(tt.password, tt.context...)
+		assert.Equal(tt.want, codes(v), "[TestEvaluate] Unexpected reasons for %q.", tt.password)
+		assert.Equal(len(tt.want) == 0, v.Allowed, "[TestEvaluate] Unexpected verdict for %q.", tt.password)
+	}
+}
+
+func TestEvaluateLengthUnicode(t *testing.T) {
+	// 8 code points, but 16 bytes.
+	v := (&Policy{}).Evaluate("пароль!1")
+	assert.True(t, v.Allowed, "[TestEvaluateLengthUnicode] Expected length to count characters, not bytes.")
+}
+
+func TestEvaluatePwned(t *testing.T) {
+	assert := assert.New(t)
+
+	p := &Policy{Ranger: &fakeRanger{}}
+
+	v := p.Evaluate("P@ssw0rd")
+	assert.False(v.Allowed)
+	assert.Equal([]Reason{{Code: Pwned, Severity: SeverityError, Params: map[string]interface{}{"count": int64(83129)}}}, v.Reasons)
+
+	p.PwnedThreshold = 100000
+	v = p.Evaluate("P@ssw0rd")
+	assert.True(v.Allowed, "[TestEvaluatePwned] Expected counts below the threshold to only warn.")
+	assert.Equal(SeverityWarning, v.Reasons[0].Severity)
+
+	assert.Empty(p.Evaluate("correct horse battery staple").Reasons)
+}
+
+func TestEvaluatePwnedUnavailable(t *testing.T) {
+	assert := assert.New(t)
+
+	p := &Policy{Ranger: &fakeRanger{err: errors.New("unreachable")}}
+
+	v := p.Evaluate("correct horse battery staple")
+	assert.True(v.Allowed, "[TestEvaluatePwnedUnavailable] Expected fail open to allow.")
+	assert.True(v.Has(PwnedCheckFailed))
+
+	p.Unavailable = password.FailClosed
+	assert.False(p.Evaluate("correct horse battery staple").Allowed, "[TestEvaluatePwnedUnavailable] Expected fail closed to reject.")
+}
+
+func TestLoadDenyList(t *testing.T) {
+	p := &Policy{}
+	if err := p.LoadDenyList(strings.NewReader("# common passwords\nqwertyuiop\n\niloveyou123\n")); err != nil {
+		t.Fatalf("[TestLoadDenyList] returned error: %v", err)
+	}
+
+	assert.True(t, p.Evaluate("ILoveYou123").Has(DenyListed))
+	assert.False(t, p.Evaluate("# common passwords").Has(DenyListed))
+}
+
+func TestVerdictJSON(t *testing.T) {
+	data, err := json.Marshal((&Policy{}).Evaluate("short"))
+	if err != nil {
+		t.Fatalf("[TestVerdictJSON] returned error: %v", err)
+	}
+	assert.JSONEq(t, `{"allowed":false,"reasons":[{"code":"too_short","severity":"error","params":{"min":8,"length":5}}]}`, string(data))
+}
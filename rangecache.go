@@ -0,0 +1,250 @@
+package gopwned
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// RangeCache keeps Pwned Passwords range responses on disk, one gzip
+	// compressed file per hash mode and prefix, so a busy host serves most
+	// lookups locally. Files older than TTL are fetched again. Once the
+	// files take more than MaxSize bytes, the least recently used ones are
+	// removed.
+	//
+	// Several processes may share a directory: files are written to a
+	// temporary file and renamed into place, the time they were fetched is
+	// kept inside each file and the time they were last used is their
+	// modification time. Each process estimates the size from its own
+	// writes and rescans the directory when the estimate exceeds MaxSize.
+	RangeCache struct {
+		TTL     time.Duration
+		MaxSize int64
+		Clock   Clock
+
+		dir  string
+		mu   sync.Mutex
+		size int64
+	}
+
+	// rangeFile is a cached range found while scanning the directory.
+	rangeFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+)
+
+// NewRangeCache creates a range cache in dir, which is created if it does not
+// exist yet. A maxSize of 0 or less means the cache is unbounded.
+func NewRangeCache(dir string, ttl time.Duration, maxSize int64) (*RangeCache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	r := &RangeCache{TTL: ttl, MaxSize: maxSize, dir: dir}
+	files, err := r.scan()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		r.size += f.size
+	}
+	return r, nil
+}
+
+func (r *RangeCache) clock() Clock {
+	if r.Clock == nil {
+		return systemClock{}
+	}
+	return r.Clock
+}
+
+// path returns the file of prefix in mode, or false if they are not safe to
+// use as file names.
+func (r *RangeCache) path(prefix, mode string) (string, bool) {
+	if len(prefix) != 5 || !isHex(prefix) || mode == "" || !isAlnum(mode) {
+		return "", false
+	}
+	return filepath.Join(r.dir, strings.ToLower(mode), strings.ToUpper(prefix)+".gz"), true
+}
+
+// Get returns the range of prefix in mode, such as "sha1" or "ntlm", if it
+// was fetched less than TTL ago, and marks it as recently used.
+func (r *RangeCache) Get(prefix, mode string) ([]byte, bool) {
+	name, ok := r.path(prefix, mode)
+	if !ok {
+		return nil, false
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, false
+	}
+	now := r.clock().Now()
+	if now.Sub(zr.ModTime) >= r.TTL {
+		return nil, false
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(zr, DefaultMaxRangeSize))
+	if err != nil {
+		return nil, false
+	}
+
+	os.Chtimes(name, now, now)
+	return body, true
+}
+
+// Set stores the range of prefix in mode. Padding entries, with a count of
+// 0, are left out, since cached lookups never leave the host.
+func (r *RangeCache) Set(prefix, mode string, body []byte) error {
+	name, ok := r.path(prefix, mode)
+	if !ok {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o700); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(name), ".tmp-")
+	if err != nil {
+		return err
+	}
+	now := r.clock().Now()
+	zw := gzip.NewWriter(tmp)
+	zw.ModTime = now
+	_, err = zw.Write(unpad(body))
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	var info os.FileInfo
+	if err == nil {
+		info, err = os.Stat(tmp.Name())
+	}
+	// A refreshed range replaces its file, whose size no longer counts.
+	var replaced int64
+	if old, serr := os.Stat(name); err == nil && serr == nil {
+		replaced = old.Size()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	os.Chtimes(name, now, now)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.size += info.Size() - replaced
+	if r.MaxSize > 0 && r.size > r.MaxSize {
+		return r.evict()
+	}
+	return nil
+}
+
+// evict removes the least recently used ranges until the cache is back
+// under 90% of MaxSize, so it does not rescan on every write.
+func (r *RangeCache) evict() error {
+	files, err := r.scan()
+	if err != nil {
+		return err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+
+	r.size = 0
+	for _, f := range files {
+		r.size += f.size
+	}
+	for _, f := range files {
+		if r.size <= r.MaxSize/10*9 {
+			break
+		}
+		if err := os.Remove(f.path); err == nil || os.IsNotExist(err) {
+			r.size -= f.size
+		}
+	}
+	return nil
+}
+
+// scan lists the cached ranges of every mode.
+func (r *RangeCache) scan() ([]rangeFile, error) {
+	var files []rangeFile
+	err := filepath.Walk(r.dir, func(name string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.HasSuffix(name, ".gz") && !strings.HasPrefix(info.Name(), ".") {
+			files = append(files, rangeFile{path: name, size: info.Size(), modTime: info.ModTime()})
+		}
+		return nil
+	})
+	return files, err
+}
+
+// rangeKey returns the prefix and hash mode of a range resource such as
+// "21BD1" or "AB1C2?mode=ntlm".
+func rangeKey(target *url.URL) (prefix, mode string) {
+	mode = target.Query().Get("mode")
+	if mode == "" {
+		mode = "sha1"
+	}
+	return path.Base(target.Path), mode
+}
+
+// unpad removes the padding entries of a range.
+func unpad(body []byte) []byte {
+	var out bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasSuffix(line, ":0") {
+			continue
+		}
+		if out.Len() > 0 {
+			out.WriteString("\r\n")
+		}
+		out.WriteString(line)
+	}
+	return out.Bytes()
+}
+
+func isHex(s string) bool {
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlnum(s string) bool {
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
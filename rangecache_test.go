@@ -0,0 +1,173 @@
+package gopwned
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mavjs/goPwned/hibptest"
+)
+
+func setupRangeCache(t *testing.T) (*hibptest.Server, *Client, *fakeClock, string) {
+	srv := hibptest.NewServer()
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+	clock := newFakeClock()
+	cache, err := NewRangeCache(dir, time.Hour, 0)
+	if err != nil {
+		t.Fatalf("NewRangeCache returned error: %v", err)
+	}
+	cache.Clock = clock
+
+	client := NewClient(nil, "")
+	client.PwnPwdURL, _ = url.Parse(srv.RangeURL())
+	client.RangeCache = cache
+	return srv, client, clock, dir
+}
+
+func TestRangeCache(t *testing.T) {
+	assert := assert.New(t)
+	srv, client, clock, dir := setupRangeCache(t)
+	metrics := &testMetrics{counts: make(map[string]int)}
+	client.Metrics = metrics
+
+	first, err := client.GetPwnedPasswords("21BD1", true)
+	assert.NoError(err)
+	assert.Contains(string(first), ":0", "[TestRangeCache] Expected the live response to be padded.")
+
+	cached, err := client.GetPwnedPasswords("21bd1", true)
+	assert.NoError(err)
+	assert.Equal(1, srv.Hits("/range/"), "[TestRangeCache] Expected the second lookup to be served from disk.")
+	assert.Contains(string(cached), "2DC183F740EE76F27B78EB39C8AD972A757:")
+	assert.NotContains(string(cached), ":0", "[TestRangeCache] Expected padding to be left out of the cache.")
+	metrics.mu.Lock()
+	assert.Equal(1, metrics.counts["cache_hits range 200"], "[TestRangeCache] Expected the hit to be counted.")
+	metrics.mu.Unlock()
+
+	_, err = client.GetPwnedPasswords("8846F?mode=ntlm", false)
+	assert.NoError(err)
+	_, err = os.Stat(filepath.Join(dir, "ntlm", "8846F.gz"))
+	assert.NoError(err, "[TestRangeCache] Expected NTLM ranges to be cached apart.")
+	_, err = os.Stat(filepath.Join(dir, "sha1", "21BD1.gz"))
+	assert.NoError(err)
+
+	clock.Advance(time.Hour)
+	_, err = client.GetPwnedPasswords("21BD1", true)
+	assert.NoError(err)
+	assert.Equal(3, srv.Hits("/range/"), "[TestRangeCache] Expected expired ranges to be fetched again.")
+}
+
+func TestRangeCacheShared(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+
+	writer, _ := NewRangeCache(dir, time.Hour, 0)
+	reader, _ := NewRangeCache(dir, time.Hour, 0)
+	assert.NoError(writer.Set("21BD1", "sha1", []byte("2DC183F740EE76F27B78EB39C8AD972A757:83129\r\nFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF:0")))
+
+	body, ok := reader.Get("21BD1", "sha1")
+	assert.True(ok, "[TestRangeCacheShared] Expected ranges written by another cache to be read.")
+	assert.Equal("2DC183F740EE76F27B78EB39C8AD972A757:83129", string(body))
+
+	_, ok = reader.Get("21BD1", "ntlm")
+	assert.False(ok, "[TestRangeCacheShared] Expected modes to be kept apart.")
+
+	assert.NoError(writer.Set("../..", "sha1", []byte("x")))
+	assert.NoError(writer.Set("21BD1", "../sha1", []byte("x")))
+	entries, _ := ioutil.ReadDir(dir)
+	assert.Len(entries, 1, "[TestRangeCacheShared] Expected unsafe keys not to be stored.")
+
+	files, _ := filepath.Glob(filepath.Join(dir, "sha1", ".tmp-*"))
+	assert.Empty(files, "[TestRangeCacheShared] Expected no temporary file to be left behind.")
+}
+
+func TestRangeCacheEviction(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	clock := newFakeClock()
+
+	body := []byte(strings.Repeat("2DC183F740EE76F27B78EB39C8AD972A757:83129\r\n", 10))
+	cache, _ := NewRangeCache(dir, time.Hour, 0)
+	cache.Clock = clock
+	cache.Set("00000", "sha1", body)
+	info, _ := os.Stat(filepath.Join(dir, "sha1", "00000.gz"))
+
+	cache.MaxSize = 4*info.Size() - 1
+	for _, prefix := range []string{"00001", "00002"} {
+		clock.Advance(time.Minute)
+		cache.Set(prefix, "sha1", body)
+	}
+	clock.Advance(time.Minute)
+	_, ok := cache.Get("00000", "sha1")
+	assert.True(ok)
+
+	clock.Advance(time.Minute)
+	cache.Set("00003", "sha1", body)
+
+	_, ok = cache.Get("00001", "sha1")
+	assert.False(ok, "[TestRangeCacheEviction] Expected the least recently used range to be evicted.")
+	for _, prefix := range []string{"00000", "00002", "00003"} {
+		_, ok = cache.Get(prefix, "sha1")
+		assert.True(ok, "[TestRangeCacheEviction] Expected %s to be kept.", prefix)
+	}
+
+	reopened, _ := NewRangeCache(dir, time.Hour, 0)
+	assert.Equal(3*info.Size(), reopened.size, "[TestRangeCacheEviction] Expected the size of existing ranges to be counted.")
+}
+
+func TestRangeCacheRewrite(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	clock := newFakeClock()
+
+	body := []byte(strings.Repeat("2DC183F740EE76F27B78EB39C8AD972A757:83129\r\n", 10))
+	cache, _ := NewRangeCache(dir, time.Hour, 0)
+	cache.Clock = clock
+	cache.Set("00000", "sha1", body)
+	info, _ := os.Stat(filepath.Join(dir, "sha1", "00000.gz"))
+
+	cache.MaxSize = 20 * info.Size()
+	clock.Advance(time.Minute)
+	cache.Set("00001", "sha1", body)
+	for i := 0; i < 10; i++ {
+		clock.Advance(time.Minute)
+		cache.Set("00001", "sha1", body)
+	}
+
+	assert.Equal(2*info.Size(), cache.size, "[TestRangeCacheRewrite] Expected a rewritten range to count once.")
+}
+
+func TestRangeCacheWriteError(t *testing.T) {
+	assert := assert.New(t)
+	_, client, _, dir := setupRangeCache(t)
+	logger := &testLogger{}
+	metrics := &testMetrics{counts: make(map[string]int)}
+	client.Logger, client.Metrics = logger, metrics
+
+	// A file where the cache expects its directory makes every write fail.
+	if err := ioutil.WriteFile(filepath.Join(dir, "sha1"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := client.GetPwnedPasswords("21BD1", false)
+	assert.NoError(err, "[TestRangeCacheWriteError] Expected the lookup to succeed without the cache.")
+	assert.Contains(string(got), "2DC183F740EE76F27B78EB39C8AD972A757:")
+
+	metrics.mu.Lock()
+	assert.Equal(1, metrics.counts["cache_errors range 200"], "[TestRangeCacheWriteError] Expected the failed write to be counted.")
+	metrics.mu.Unlock()
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	found := false
+	for _, record := range logger.records {
+		found = found || strings.HasPrefix(record, "WARN gopwned: cache write failed")
+	}
+	assert.True(found, "[TestRangeCacheWriteError] Expected the failed write to be logged. Got: %v", logger.records)
+}
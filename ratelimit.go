@@ -0,0 +1,76 @@
+package gopwned
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter spaces out requests so no more than a given number are sent per
+// minute, matching the RPM of an HIBP API key. Callers are served in the
+// order they call Wait. A Limiter is safe for concurrent use.
+type Limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// NewLimiter creates a limiter allowing rpm requests per minute. An rpm of 0
+// or less disables limiting.
+func NewLimiter(rpm int) *Limiter {
+	l := &Limiter{}
+	if rpm > 0 {
+		l.interval = time.Minute / time.Duration(rpm)
+	}
+	return l
+}
+
+// Reserve books the next free slot and returns how long the caller has to
+// wait before using it.
+func (l *Limiter) Reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	return delay
+}
+
+// Wait blocks until the caller may send its request, or until ctx is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	delay := l.Reserve()
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ready returns when the next free slot starts, which may be in the past.
+func (l *Limiter) ready() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.next
+}
+
+// pause holds back every slot until d from now, e.g. after a 429.
+func (l *Limiter) pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if until := time.Now().Add(d); l.next.Before(until) {
+		l.next = until
+	}
+}
@@ -0,0 +1,89 @@
+package gopwned
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiterReserve(t *testing.T) {
+	assert := assert.New(t)
+
+	limiter := NewLimiter(60)
+
+	assert.Equal(time.Duration(0), limiter.Reserve(), "[TestLimiterReserve] Expected the first request not to wait.")
+
+	delay := limiter.Reserve()
+	assert.True(delay > 900*time.Millisecond && delay <= time.Second, "[TestLimiterReserve] Expected the second request to wait a second. Got: %v", delay)
+
+	delay = limiter.Reserve()
+	assert.True(delay > 1900*time.Millisecond && delay <= 2*time.Second, "[TestLimiterReserve] Expected the third request to queue behind the second. Got: %v", delay)
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	limiter := NewLimiter(0)
+	for i := 0; i < 10; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatalf("[TestLimiterDisabled] returned error: %v", err)
+		}
+	}
+}
+
+func TestLimiterCancel(t *testing.T) {
+	limiter := NewLimiter(1)
+	limiter.Reserve()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.Equal(t, context.DeadlineExceeded, limiter.Wait(ctx), "[TestLimiterCancel] Expected Wait to return once the context is done.")
+}
+
+// cancelTracer starts every call on a context that is already cancelled.
+type cancelTracer struct{}
+
+func (cancelTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	return ctx, &testSpan{attrs: make(map[string]interface{})}
+}
+
+func TestLimiterSkipsCacheHits(t *testing.T) {
+	assert := assert.New(t)
+
+	hits := 0
+	gopwned := setupCacheServer(t, func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		fmt.Fprint(w, `["Account balances","Age groups"]`)
+	})
+	gopwned.Limiter = NewLimiter(1)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := gopwned.GetDataClasses(); err != nil {
+			t.Fatalf("[TestLimiterSkipsCacheHits] returned error: %v", err)
+		}
+	}
+	assert.True(time.Since(start) < time.Second, "[TestLimiterSkipsCacheHits] Expected cache hits not to wait for the limiter.")
+	assert.Equal(1, hits, "[TestLimiterSkipsCacheHits] Expected a single request to the API.")
+}
+
+func TestLimiterWaitError(t *testing.T) {
+	assert := assert.New(t)
+
+	hits := 0
+	gopwned := setupCacheServer(t, func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		fmt.Fprint(w, `[{"Name":"Adobe"}]`)
+	})
+	gopwned.Limiter = NewLimiter(1)
+	gopwned.Tracer = cancelTracer{}
+
+	_, err := gopwned.GetAccountBreaches("foo@bar.com", "", true, false)
+	assert.Equal(context.Canceled, err, "[TestLimiterWaitError] Expected the error of the limiter to be returned.")
+	assert.Equal(0, hits, "[TestLimiterWaitError] Expected no request once waiting failed.")
+}
@@ -0,0 +1,272 @@
+// Package replay provides an http.RoundTripper that records real HTTP
+// interactions into golden files once and replays them afterwards, so tests
+// against the HIBP API run offline and deterministically.
+//
+// Each golden file, or cassette, holds the interactions of one test in the
+// order they happened. Secrets such as the `hibp-api-key` header are scrubbed
+// before a cassette is written, so recordings can be committed. A cassette
+// can also be written by hand in the same format, as a synthetic fixture.
+//
+//	rec, err := replay.New("testdata/replay/TestAccountExists.json", replay.Replaying)
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	client := gopwned.NewClient(&http.Client{Transport: rec}, key)
+package replay
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+type (
+	// Mode tells a Transport whether to record or to replay.
+	Mode int
+
+	// Transport records or replays HTTP interactions. In Recording mode
+	// requests are sent through Base and kept until Save is called; in
+	// Replaying mode they are answered from the cassette without touching the
+	// network.
+	Transport struct {
+		// Base sends the requests while recording. It defaults to
+		// http.DefaultTransport.
+		Base http.RoundTripper
+
+		// Scrub lists the request and response headers whose values are
+		// replaced by Redacted in the cassette.
+		Scrub []string
+
+		// Match lists the request headers, besides the method and the URL,
+		// that must be equal for a recording to answer a request.
+		Match []string
+
+		path string
+		mode Mode
+
+		mu           sync.Mutex
+		interactions []*Interaction
+		used         []bool
+	}
+
+	// Interaction is a recorded request and its response.
+	Interaction struct {
+		Request  Request  `json:"request"`
+		Response Response `json:"response"`
+	}
+
+	// Request is the recorded part of an HTTP request.
+	Request struct {
+		Method string      `json:"method"`
+		URL    string      `json:"url"`
+		Header http.Header `json:"header,omitempty"`
+	}
+
+	// Response is the recorded part of an HTTP response. Bodies that are
+	// not valid UTF-8, such as gzip compressed ones, are stored base64
+	// encoded and BodyEncoding is "base64".
+	Response struct {
+		StatusCode   int         `json:"status_code"`
+		Header       http.Header `json:"header,omitempty"`
+		Body         string      `json:"body"`
+		BodyEncoding string      `json:"body_encoding,omitempty"`
+	}
+
+	cassette struct {
+		Interactions []*Interaction `json:"interactions"`
+	}
+)
+
+const (
+	// Replaying answers requests from the cassette.
+	Replaying Mode = iota
+
+	// Recording sends requests to the network and records them.
+	Recording
+)
+
+// Redacted replaces the values of scrubbed headers.
+const Redacted = "REDACTED"
+
+var (
+	// DefaultScrub - headers carrying secrets of the HIBP APIs.
+	DefaultScrub = []string{"hibp-api-key", "Authorization", "Cookie", "Set-Cookie"}
+
+	// DefaultMatch - headers that change the response of the HIBP APIs.
+	DefaultMatch = []string{"Add-Padding"}
+)
+
+// New returns a Transport for the cassette at path. When replaying, the
+// cassette is loaded and must exist.
+func New(path string, mode Mode) (*Transport, error) {
+	t := &Transport{
+		Scrub: DefaultScrub,
+		Match: DefaultMatch,
+		path:  path,
+		mode:  mode,
+	}
+	if mode == Recording {
+		return t, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("replay: %w (record it with -record)", err)
+	}
+	var c cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("replay: %s: %w", path, err)
+	}
+	t.interactions = c.Interactions
+	t.used = make([]bool, len(c.Interactions))
+	return t, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.mode == Recording {
+		return t.record(req)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i, in := range t.interactions {
+		if !t.used[i] && t.matches(&in.Request, req) {
+			t.used[i] = true
+			return in.Response.httpResponse(req), nil
+		}
+	}
+	return nil, fmt.Errorf("replay: no recording of %s %s in %s (record it with -record)", req.Method, req.URL, t.path)
+}
+
+func (t *Transport) record(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	in := &Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: t.scrub(req.Header),
+		},
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     t.scrub(resp.Header),
+			Body:       string(body),
+		},
+	}
+	if !utf8.Valid(body) {
+		in.Response.Body = base64.StdEncoding.EncodeToString(body)
+		in.Response.BodyEncoding = "base64"
+	}
+
+	t.mu.Lock()
+	t.interactions = append(t.interactions, in)
+	t.mu.Unlock()
+
+	return in.Response.httpResponse(req), nil
+}
+
+// Save writes the recorded interactions to the cassette. It does nothing
+// when replaying.
+func (t *Transport) Save() error {
+	if t.mode != Recording {
+		return nil
+	}
+
+	t.mu.Lock()
+	data, err := json.MarshalIndent(cassette{Interactions: t.interactions}, "", "  ")
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(t.path), 0755); err != nil {
+		return err
+	}
+	tmp := t.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, t.path)
+}
+
+func (t *Transport) matches(recorded *Request, req *http.Request) bool {
+	if recorded.Method != req.Method || !sameURL(recorded.URL, req.URL) {
+		return false
+	}
+	for _, name := range t.Match {
+		if recorded.Header.Get(name) != req.Header.Get(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameURL reports whether the recorded URL and u address the same resource.
+// Paths are compared decoded, so "%40" in a cassette matches "@" in the
+// request and cassettes stay valid when the escaping of paths changes.
+func sameURL(recorded string, u *url.URL) bool {
+	r, err := url.Parse(recorded)
+	if err != nil {
+		return false
+	}
+	return r.Scheme == u.Scheme && r.Host == u.Host && r.Path == u.Path && r.RawQuery == u.RawQuery
+}
+
+// scrub returns a copy of header with the values of secret headers redacted.
+func (t *Transport) scrub(header http.Header) http.Header {
+	clean := header.Clone()
+	for name := range clean {
+		for _, secret := range t.Scrub {
+			if strings.EqualFold(name, secret) {
+				clean[name] = []string{Redacted}
+			}
+		}
+	}
+	return clean
+}
+
+func (r *Response) httpResponse(req *http.Request) *http.Response {
+	body := []byte(r.Body)
+	if r.BodyEncoding == "base64" {
+		if decoded, err := base64.StdEncoding.DecodeString(r.Body); err == nil {
+			body = decoded
+		}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
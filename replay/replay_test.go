@@ -0,0 +1,132 @@
+package replay
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func get(t *testing.T, client *http.Client, target string, header map[string]string) (int, string) {
+	req, _ := http.NewRequest("GET", target, nil)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("unable to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestRecordReplay(t *testing.T) {
+	assert := assert.New(t)
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret"})
+		fmt.Fprintf(w, "call %d padding=%s", calls, r.Header.Get("Add-Padding"))
+	}))
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	rec, err := New(path, Recording)
+	if err != nil {
+		t.Fatalf("[TestRecordReplay] returned error: %v", err)
+	}
+	client := &http.Client{Transport: rec}
+	get(t, client, server.URL+"/range/21BD1", map[string]string{"hibp-api-key": "live-key"})
+	get(t, client, server.URL+"/range/21BD1", map[string]string{"Add-Padding": "true"})
+	get(t, client, server.URL+"/range/21BD1", nil)
+	if err := rec.Save(); err != nil {
+		t.Fatalf("[TestRecordReplay] Save returned error: %v", err)
+	}
+	server.Close()
+
+	data, _ := ioutil.ReadFile(path)
+	assert.NotContains(string(data), "live-key", "[TestRecordReplay] Expected the API key to be scrubbed.")
+	assert.NotContains(string(data), "secret", "[TestRecordReplay] Expected cookies to be scrubbed.")
+	assert.Contains(string(data), Redacted)
+
+	rec, err = New(path, Replaying)
+	if err != nil {
+		t.Fatalf("[TestRecordReplay] returned error: %v", err)
+	}
+	client = &http.Client{Transport: rec}
+
+	status, body := get(t, client, server.URL+"/range/21BD1", map[string]string{"Add-Padding": "true"})
+	assert.Equal(http.StatusOK, status)
+	assert.Equal("call 2 padding=true", body, "[TestRecordReplay] Expected the recording matching Add-Padding.")
+
+	_, body = get(t, client, server.URL+"/range/21BD1", nil)
+	assert.Equal("call 1 padding=", body, "[TestRecordReplay] Expected identical requests to replay in order.")
+	_, body = get(t, client, server.URL+"/range/21BD1", nil)
+	assert.Equal("call 3 padding=", body)
+
+	_, err = client.Get(server.URL + "/range/21BD1")
+	if assert.Error(err, "[TestRecordReplay] Expected every recording to be used once.") {
+		assert.True(strings.Contains(err.Error(), "no recording"), "[TestRecordReplay] Unexpected error: %v", err)
+	}
+}
+
+func TestMissingCassette(t *testing.T) {
+	_, err := New(filepath.Join(t.TempDir(), "missing.json"), Replaying)
+	assert.Error(t, err, "[TestMissingCassette] Expected an error for a missing cassette.")
+}
+
+func TestRecordBinaryBody(t *testing.T) {
+	assert := assert.New(t)
+
+	binary := []byte{0x1f, 0x8b, 0x08, 0x00, 0xff, 0xfe}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(binary)
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	rec, _ := New(path, Recording)
+	get(t, &http.Client{Transport: rec}, server.URL+"/range/21BD1", nil)
+	if err := rec.Save(); err != nil {
+		t.Fatalf("[TestRecordBinaryBody] Save returned error: %v", err)
+	}
+
+	data, _ := ioutil.ReadFile(path)
+	assert.Contains(string(data), `"body_encoding": "base64"`, "[TestRecordBinaryBody] Expected the body to be stored base64 encoded.")
+
+	rec, _ = New(path, Replaying)
+	_, body := get(t, &http.Client{Transport: rec}, server.URL+"/range/21BD1", nil)
+	assert.Equal(string(binary), body, "[TestRecordBinaryBody] Expected the body to replay unchanged.")
+}
+
+func TestReplayDecodedPath(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "found")
+	}))
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	rec, _ := New(path, Recording)
+	get(t, &http.Client{Transport: rec}, server.URL+"/breachedaccount/foo%40bar.com?truncateResponse=true", nil)
+	if err := rec.Save(); err != nil {
+		t.Fatalf("[TestReplayDecodedPath] Save returned error: %v", err)
+	}
+	server.Close()
+
+	rec, _ = New(path, Replaying)
+	status, body := get(t, &http.Client{Transport: rec}, server.URL+"/breachedaccount/foo@bar.com?truncateResponse=true", nil)
+	assert.Equal(http.StatusOK, status)
+	assert.Equal("found", body, "[TestReplayDecodedPath] Expected an escaped path to match the same path unescaped.")
+
+	_, err := (&http.Client{Transport: rec}).Get(server.URL + "/breachedaccount/foo@bar.com?truncateResponse=false")
+	assert.Error(err, "[TestReplayDecodedPath] Expected a different query not to match.")
+}
@@ -0,0 +1,100 @@
+package gopwned
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Response holds the metadata of the last API call of a client returned by
+// `WithResponse`, to debug throttling and caching without a packet capture.
+type Response struct {
+	// StatusCode is the status of the response, 0 if none was received. A
+	// cached entry revalidated by the API has status 304.
+	StatusCode int
+
+	// Header holds every header of the response.
+	Header http.Header
+
+	// RetryAfter is how long the API asked to wait before trying again.
+	RetryAfter time.Duration
+
+	// CFRay identifies the request at Cloudflare, for support requests.
+	CFRay string
+
+	// Age, CacheControl and CacheStatus describe how caches along the way
+	// handled the response.
+	Age          time.Duration
+	CacheControl string
+	CacheStatus  string
+
+	// ETag and LastModified are the validators of the response body.
+	ETag         string
+	LastModified string
+
+	// FromCache is true if the call was answered from the client's cache,
+	// and Retries counts the requests sent again by middleware.
+	FromCache bool
+	Retries   int
+
+	// Elapsed is the time the call took, including rate limiting.
+	Elapsed time.Duration
+}
+
+// WithResponse returns a copy of the client that stores the metadata of
+// every call into meta, overwriting it each time. Calls of the copy must not
+// run concurrently, as they share meta.
+//
+//	var meta gopwned.Response
+//	_, err := client.WithResponse(&meta).GetAccountBreaches("foo@example.com", "", true, false)
+//	log.Printf("status %d, retry after %s, ray %s", meta.StatusCode, meta.RetryAfter, meta.CFRay)
+func (c *Client) WithResponse(meta *Response) *Client {
+	cp := *c
+	cp.response = meta
+	return &cp
+}
+
+// fill stores the metadata of the call into meta.
+func (o *call) fill(meta *Response, retries int) {
+	h := o.header
+	*meta = Response{
+		StatusCode:   o.status,
+		Header:       h,
+		RetryAfter:   retryAfter(h.Get("Retry-After")),
+		CFRay:        h.Get("CF-Ray"),
+		CacheControl: h.Get("Cache-Control"),
+		CacheStatus:  h.Get("CF-Cache-Status"),
+		ETag:         h.Get("ETag"),
+		LastModified: h.Get("Last-Modified"),
+		FromCache:    o.cacheHit,
+		Retries:      retries,
+		Elapsed:      time.Since(o.start),
+	}
+	if age, err := strconv.Atoi(h.Get("Age")); err == nil {
+		meta.Age = time.Duration(age) * time.Second
+	}
+	if o.entry != nil {
+		if meta.ETag == "" {
+			meta.ETag = o.entry.ETag
+		}
+		if meta.LastModified == "" {
+			meta.LastModified = o.entry.LastModified
+		}
+	}
+}
+
+// retryAfter parses a "Retry-After" header, given in seconds or as a date.
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
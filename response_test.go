@@ -0,0 +1,87 @@
+package gopwned
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mavjs/goPwned/hibptest"
+)
+
+func TestWithResponse(t *testing.T) {
+	assert := assert.New(t)
+
+	mockHandler.HandleFunc("/breach/Meta", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("CF-Ray", "8a1b2c3d4e5f6789-AMS")
+		w.Header().Set("CF-Cache-Status", "HIT")
+		w.Header().Set("Age", "120")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		w.Header().Set("ETag", `"meta-v1"`)
+		w.Header().Set("Last-Modified", "Mon, 19 Oct 2026 08:00:00 GMT")
+		fmt.Fprint(w, `{"Name": "Meta"}`)
+	})
+
+	client := NewClient(nil, "")
+	client.BaseURL, _ = url.Parse(mockServer.URL)
+	client.Cache = NewMemoryCache(10)
+
+	var meta Response
+	withMeta := client.WithResponse(&meta)
+
+	_, err := withMeta.GetABreachedSite("Meta")
+	assert.NoError(err)
+	assert.Equal(http.StatusOK, meta.StatusCode)
+	assert.Equal("8a1b2c3d4e5f6789-AMS", meta.CFRay)
+	assert.Equal("HIT", meta.CacheStatus)
+	assert.Equal(2*time.Minute, meta.Age)
+	assert.Equal("public, max-age=300", meta.CacheControl)
+	assert.Equal(`"meta-v1"`, meta.ETag)
+	assert.Equal("Mon, 19 Oct 2026 08:00:00 GMT", meta.LastModified)
+	assert.False(meta.FromCache)
+	assert.True(meta.Elapsed > 0, "[TestWithResponse] Expected the elapsed time.")
+
+	_, err = withMeta.GetABreachedSite("Meta")
+	assert.NoError(err)
+	assert.True(meta.FromCache, "[TestWithResponse] Expected the second call to be answered from the cache.")
+	assert.Equal(http.StatusOK, meta.StatusCode)
+	assert.Equal(`"meta-v1"`, meta.ETag, "[TestWithResponse] Expected the validators of the cached entry.")
+
+	assert.Nil(client.response, "[TestWithResponse] Expected the original client to be left alone.")
+}
+
+func TestWithResponseThrottled(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := hibptest.NewServer()
+	defer srv.Close()
+	srv.Fail("/api/v3/breachedaccount/", hibptest.Fault{Status: http.StatusTooManyRequests, RetryAfter: 7, Count: 1})
+
+	client := NewClient(nil, hibptest.APIKey)
+	client.BaseURL, _ = url.Parse(srv.BaseURL())
+
+	var meta Response
+	_, err := client.WithResponse(&meta).GetAccountBreaches("account-exists@"+hibptest.Domain, "", true, false)
+	assert.EqualError(err, respCodes[429])
+	assert.Equal(http.StatusTooManyRequests, meta.StatusCode, "[TestWithResponseThrottled] Expected metadata of failed calls too.")
+	assert.Equal(7*time.Second, meta.RetryAfter)
+
+	srv.Close()
+	_, err = client.WithResponse(&meta).GetBreachedSites("")
+	assert.Error(err)
+	assert.Equal(0, meta.StatusCode, "[TestWithResponseThrottled] Expected no status without a response.")
+}
+
+func TestRetryAfter(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(time.Duration(0), retryAfter(""))
+	assert.Equal(3*time.Second, retryAfter("3"))
+	assert.Equal(time.Duration(0), retryAfter("soon"))
+
+	d := retryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	assert.True(d > 55*time.Second && d <= time.Minute, "[TestRetryAfter] Expected a date to be converted to a duration. Got: %v", d)
+}
@@ -0,0 +1,41 @@
+package gopwned
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+)
+
+// maxDrain - how much of an unread body is read to keep its connection
+// open for reuse. Longer bodies are cheaper to drop with the connection.
+const maxDrain = 64 << 10
+
+// NewTransport returns the http.Transport used by NewClient when no
+// http.Client is given. It keeps connections alive and pools enough of them
+// per host for concurrent range lookups, and negotiates HTTP/2 when the
+// server supports it.
+func NewTransport() *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   32,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// closeBody reads what is left of body, up to maxDrain, and closes it, so the
+// connection goes back to the pool instead of being torn down.
+func closeBody(body io.ReadCloser) error {
+	io.Copy(ioutil.Discard, io.LimitReader(body, maxDrain))
+	return body.Close()
+}
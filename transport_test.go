@@ -0,0 +1,115 @@
+package gopwned
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTLSServer starts an HTTP/2 server answering range and catalogue lookups,
+// and counts the connections opened to it.
+func newTLSServer(t testing.TB) (*httptest.Server, func() int) {
+	var mu sync.Mutex
+	conns := 0
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Proto", r.Proto)
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/range/"):
+			w.Write([]byte(strings.Repeat("0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n", 800)))
+		case r.URL.Path == "/breach/Missing":
+			http.Error(w, strings.Repeat("not found ", 100), http.StatusNotFound)
+		default:
+			w.Write([]byte(`["Passwords"]`))
+		}
+	}))
+	server.EnableHTTP2 = true
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			conns++
+			mu.Unlock()
+		}
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	return server, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return conns
+	}
+}
+
+// newTLSClient returns a client with the default transport, trusting server.
+func newTLSClient(server *httptest.Server) *Client {
+	transport := NewTransport()
+	transport.TLSClientConfig = server.Client().Transport.(*http.Transport).TLSClientConfig
+
+	client := NewClient(&http.Client{Transport: transport}, "")
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	client.PwnPwdURL, _ = url.Parse(server.URL + "/range/")
+	return client
+}
+
+func TestConnectionReuse(t *testing.T) {
+	assert := assert.New(t)
+	server, conns := newTLSServer(t)
+	client := newTLSClient(server)
+
+	var meta Response
+	for i := 0; i < 5; i++ {
+		_, err := client.WithResponse(&meta).GetPwnedPasswords("21BD1", false)
+		assert.NoError(err, "[TestConnectionReuse] Range lookups should succeed")
+	}
+	_, err := client.GetABreachedSite("Missing")
+	assert.Equal(ErrNotFound, err, "[TestConnectionReuse] Unknown breach should not be found")
+	_, err = client.GetDataClasses()
+	assert.NoError(err, "[TestConnectionReuse] Lookups after an error should succeed")
+
+	assert.Equal("HTTP/2.0", meta.Header.Get("X-Proto"), "[TestConnectionReuse] HTTP/2 should be negotiated")
+	assert.Equal(1, conns(), "[TestConnectionReuse] Sequential lookups should reuse one connection")
+}
+
+func TestNewClientTransport(t *testing.T) {
+	client := NewClient(nil, "")
+	transport, ok := client.client.Transport.(*http.Transport)
+	if assert.True(t, ok, "[TestNewClientTransport] Default client should use an *http.Transport") {
+		assert.True(t, transport.ForceAttemptHTTP2, "[TestNewClientTransport] Default transport should attempt HTTP/2")
+		assert.True(t, transport.MaxIdleConnsPerHost > http.DefaultMaxIdleConnsPerHost, "[TestNewClientTransport] Default transport should keep more idle connections per host")
+	}
+}
+
+func BenchmarkRangeKeepAlive(b *testing.B) {
+	server, _ := newTLSServer(b)
+	benchmarkRange(b, newTLSClient(server))
+}
+
+func BenchmarkRangeNewConnection(b *testing.B) {
+	server, _ := newTLSServer(b)
+	client := newTLSClient(server)
+	client.Use(func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			req.Close = true
+			return next.Do(req)
+		})
+	})
+	benchmarkRange(b, client)
+}
+
+func benchmarkRange(b *testing.B, client *Client) {
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := client.GetPwnedPasswords("21BD1", false); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package gopwned
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/mavjs/goPwned/account"
+)
+
+// VariantBreach is a breach found for one or more variants of an account,
+// listed in the order they were checked.
+type VariantBreach struct {
+	*Breach
+	Variants []string
+}
+
+// CheckAccountVariants - checks every variant of an email address produced by
+// expander, such as the address without its "+tag", and merges the breaches
+// found, attributing each one to the variants it was found for. The variants
+// are checked one after the other through `GetAccountBreaches`, so the
+// `Limiter` of the client applies. Variants that are not found are skipped.
+func (c *Client) CheckAccountVariants(email string, expander account.Expander, truncate, unverified bool) ([]*VariantBreach, error) {
+	variants, err := expander.Variants(email)
+	if err != nil {
+		return nil, err
+	}
+
+	var merged []*VariantBreach
+	byName := make(map[string]*VariantBreach)
+	for _, variant := range variants {
+		breaches, err := c.GetAccountBreaches(variant, "", truncate, unverified)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, b := range breaches {
+			key := strings.ToLower(b.Name)
+			if vb, ok := byName[key]; ok {
+				vb.Variants = append(vb.Variants, variant)
+				continue
+			}
+			vb := &VariantBreach{Breach: b, Variants: []string{variant}}
+			byName[key] = vb
+			merged = append(merged, vb)
+		}
+	}
+	return merged, nil
+}
@@ -0,0 +1,44 @@
+package gopwned
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mavjs/goPwned/account"
+	"github.com/mavjs/goPwned/hibptest"
+)
+
+func TestCheckAccountVariants(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := hibptest.NewServer()
+	defer srv.Close()
+	srv.Accounts["john.doe@gmail.com"] = &hibptest.Account{Breaches: []string{"Adobe"}}
+	srv.Accounts["johndoe@googlemail.com"] = &hibptest.Account{Breaches: []string{"Gawker", "Adobe"}}
+
+	client := NewClient(nil, hibptest.APIKey)
+	client.BaseURL, _ = url.Parse(srv.BaseURL())
+
+	got, err := client.CheckAccountVariants("John.Doe+Shop@gmail.com", account.DefaultExpander, true, true)
+	if err != nil {
+		t.Fatalf("[TestCheckAccountVariants] returned error: %v", err)
+	}
+	if assert.Len(got, 2) {
+		assert.Equal("Adobe", got[0].Name)
+		assert.Equal([]string{"john.doe@gmail.com", "johndoe@googlemail.com"}, got[0].Variants, "[TestCheckAccountVariants] Expected the breach attributed to every matching variant.")
+		assert.Equal("Gawker", got[1].Name)
+		assert.Equal([]string{"johndoe@googlemail.com"}, got[1].Variants)
+	}
+	assert.Equal(8, srv.Hits("/api/v3/breachedaccount/"), "[TestCheckAccountVariants] Expected every variant to be checked.")
+
+	got, err = client.CheckAccountVariants("John.Doe+Shop@gmail.com", account.Expander{}, true, true)
+	assert.NoError(err)
+	assert.Empty(got, "[TestCheckAccountVariants] Expected only the address itself without rules.")
+
+	srv.Fail("/api/v3/breachedaccount/", hibptest.Fault{Status: http.StatusServiceUnavailable})
+	_, err = client.CheckAccountVariants("john.doe@gmail.com", account.DefaultExpander, true, true)
+	assert.EqualError(err, respCodes[503], "[TestCheckAccountVariants] Expected failures other than 404 to be returned.")
+}
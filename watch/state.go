@@ -0,0 +1,108 @@
+package watch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// State is what a watcher remembers between restarts: the breaches it has
+// already seen in the catalogue, the findings it has already notified and,
+// for findings some sinks failed to receive, the sinks that did receive
+// them, by their position in the watcher's sinks.
+type State struct {
+	path      string
+	Known     map[string]bool         `json:"known_breaches"`
+	Seen      map[string]time.Time    `json:"seen"`
+	Delivered map[string]map[int]bool `json:"delivered,omitempty"`
+}
+
+// LoadState reads the state file at path. A missing file results in an empty
+// state, an empty path keeps the state in memory only.
+func LoadState(path string) (*State, error) {
+	s := &State{path: path, Known: make(map[string]bool), Seen: make(map[string]time.Time), Delivered: make(map[string]map[int]bool)}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	if s.Known == nil {
+		s.Known = make(map[string]bool)
+	}
+	if s.Seen == nil {
+		s.Seen = make(map[string]time.Time)
+	}
+	if s.Delivered == nil {
+		s.Delivered = make(map[string]map[int]bool)
+	}
+	return s, nil
+}
+
+// Save writes the state to its file, replacing it atomically.
+func (s *State) Save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), ".watch-")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+func (s *State) knows(breach string) bool {
+	return s.Known[breach]
+}
+
+func (s *State) addKnown(breach string) {
+	s.Known[breach] = true
+}
+
+func (s *State) seen(key string) bool {
+	_, ok := s.Seen[key]
+	return ok
+}
+
+func (s *State) markSeen(key string, at time.Time) {
+	s.Seen[key] = at
+	delete(s.Delivered, key)
+}
+
+func (s *State) delivered(key string, sink int) bool {
+	return s.Delivered[key][sink]
+}
+
+func (s *State) markDelivered(key string, sink int) {
+	if s.Delivered[key] == nil {
+		s.Delivered[key] = make(map[int]bool)
+	}
+	s.Delivered[key][sink] = true
+}
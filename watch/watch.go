@@ -0,0 +1,208 @@
+// Package watch monitors a watchlist of accounts and domains for new
+// breaches. It polls the breach catalogue on a schedule and, whenever a new
+// breach shows up, re-checks every target and notifies the configured sinks
+// about findings that were not seen before.
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	gopwned "github.com/mavjs/goPwned"
+	"github.com/mavjs/goPwned/notify"
+)
+
+type (
+	// API is the subset of `*gopwned.Client` used by a Watcher.
+	API interface {
+		GetLatestBreach() (*gopwned.Breach, error)
+		GetBreachedSites(domainFilter string) ([]*gopwned.Breach, error)
+		GetAccountBreaches(account, domain string, truncate, unverified bool) ([]*gopwned.Breach, error)
+		GetDomainBreaches(domain string) (gopwned.DomainBreaches, error)
+	}
+
+	// Config describes what to watch and how often.
+	Config struct {
+		Accounts []string
+		Domains  []string
+		// Interval between two polls, defaults to DefaultInterval.
+		Interval time.Duration
+		// State is the path of the state file. An empty path keeps the state
+		// in memory, so every restart notifies all findings again.
+		State string
+	}
+
+	// Watcher polls the API and notifies its sinks about new findings.
+	Watcher struct {
+		api    API
+		config Config
+		sinks  []notify.Sink
+		state  *State
+
+		// Logger receives errors that do not stop the watcher, such as a
+		// failed poll. It defaults to the standard logger.
+		Logger *log.Logger
+	}
+)
+
+// DefaultInterval - how often the watcher polls when no interval is set.
+const DefaultInterval = time.Hour
+
+// New creates a watcher for config, loading its state file if it exists.
+func New(api API, config Config, sinks ...notify.Sink) (*Watcher, error) {
+	if config.Interval <= 0 {
+		config.Interval = DefaultInterval
+	}
+
+	state, err := LoadState(config.State)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Watcher{api: api, config: config, sinks: sinks, state: state, Logger: log.Default()}, nil
+}
+
+// Run polls until ctx is done. A failed poll is logged and retried on the
+// next tick.
+func (w *Watcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.Poll(); err != nil {
+			w.Logger.Printf("watch: poll failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll checks once for new breaches and, if there are any, re-checks the
+// watchlist. It returns the findings that were notified.
+func (w *Watcher) Poll() ([]*notify.Finding, error) {
+	latest, err := w.api.GetLatestBreach()
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil && w.state.knows(latest.Name) {
+		return nil, nil
+	}
+
+	breaches, err := w.api.GetBreachedSites("")
+	if err != nil {
+		return nil, err
+	}
+	catalogue := make(map[string]*gopwned.Breach, len(breaches))
+	for _, b := range breaches {
+		catalogue[b.Name] = b
+	}
+
+	findings, checkErr := w.check(catalogue)
+
+	var notified []*notify.Finding
+	failed := false
+	for _, f := range findings {
+		if w.state.seen(f.Key()) {
+			continue
+		}
+		if err := w.notify(f); err != nil {
+			// Leave the finding unseen so it is retried on the next poll.
+			w.Logger.Printf("watch: %v", err)
+			failed = true
+			continue
+		}
+		w.state.markSeen(f.Key(), f.FoundAt)
+		notified = append(notified, f)
+	}
+
+	// Only remember the catalogue once every target was checked and every
+	// finding delivered, otherwise the next poll would skip the targets that
+	// failed and the findings still waiting for a sink.
+	if checkErr == nil && !failed {
+		for name := range catalogue {
+			w.state.addKnown(name)
+		}
+	}
+
+	if err := w.state.Save(); err != nil {
+		return notified, err
+	}
+	return notified, checkErr
+}
+
+// check looks up every target of the watchlist.
+func (w *Watcher) check(catalogue map[string]*gopwned.Breach) ([]*notify.Finding, error) {
+	var findings []*notify.Finding
+	var errs []error
+	now := time.Now().UTC()
+
+	for _, account := range w.config.Accounts {
+		breaches, err := w.api.GetAccountBreaches(account, "", true, true)
+		if errors.Is(err, gopwned.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("account %s: %w", account, err))
+			continue
+		}
+		for _, b := range breaches {
+			findings = append(findings, &notify.Finding{Kind: notify.AccountTarget, Target: account, Breach: lookup(catalogue, b.Name), FoundAt: now})
+		}
+	}
+
+	for _, domain := range w.config.Domains {
+		aliases, err := w.api.GetDomainBreaches(domain)
+		if errors.Is(err, gopwned.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("domain %s: %w", domain, err))
+			continue
+		}
+		for alias, names := range aliases {
+			for _, name := range names {
+				findings = append(findings, &notify.Finding{Kind: notify.DomainTarget, Target: domain, Alias: alias, Breach: lookup(catalogue, name), FoundAt: now})
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return findings, fmt.Errorf("%d of %d targets failed, first error: %w", len(errs), len(w.config.Accounts)+len(w.config.Domains), errs[0])
+	}
+	return findings, nil
+}
+
+// notify sends f to every sink that has not received it yet. Sinks that
+// succeed are remembered, so a retry only goes to the ones that failed.
+func (w *Watcher) notify(f *notify.Finding) error {
+	var first error
+	for i, sink := range w.sinks {
+		if w.state.delivered(f.Key(), i) {
+			continue
+		}
+		if err := sink.Notify(f); err != nil {
+			if first == nil {
+				first = fmt.Errorf("notifying %s: %w", f.Key(), err)
+			}
+			continue
+		}
+		w.state.markDelivered(f.Key(), i)
+	}
+	return first
+}
+
+// lookup returns the full breach from the catalogue, or a breach holding
+// only its name if the catalogue does not know it yet.
+func lookup(catalogue map[string]*gopwned.Breach, name string) *gopwned.Breach {
+	if b, ok := catalogue[name]; ok {
+		return b
+	}
+	return &gopwned.Breach{Name: name}
+}
@@ -0,0 +1,199 @@
+package watch
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	gopwned "github.com/mavjs/goPwned"
+	"github.com/mavjs/goPwned/notify"
+)
+
+// fakeAPI serves a fixed catalogue and watchlist results.
+type fakeAPI struct {
+	breaches []*gopwned.Breach
+	accounts map[string][]string
+	domains  map[string]gopwned.DomainBreaches
+
+	catalogueCalls int
+}
+
+func (f *fakeAPI) GetLatestBreach() (*gopwned.Breach, error) {
+	return f.breaches[len(f.breaches)-1], nil
+}
+
+func (f *fakeAPI) GetBreachedSites(domainFilter string) ([]*gopwned.Breach, error) {
+	f.catalogueCalls++
+	return f.breaches, nil
+}
+
+func (f *fakeAPI) GetAccountBreaches(account, domain string, truncate, unverified bool) ([]*gopwned.Breach, error) {
+	names, ok := f.accounts[account]
+	if !ok {
+		return nil, gopwned.ErrNotFound
+	}
+	var breaches []*gopwned.Breach
+	for _, name := range names {
+		breaches = append(breaches, &gopwned.Breach{Name: name})
+	}
+	return breaches, nil
+}
+
+func (f *fakeAPI) GetDomainBreaches(domain string) (gopwned.DomainBreaches, error) {
+	aliases, ok := f.domains[domain]
+	if !ok {
+		return nil, gopwned.ErrNotFound
+	}
+	return aliases, nil
+}
+
+func (f *fakeAPI) addBreach(name string) {
+	f.breaches = append(f.breaches, &gopwned.Breach{Name: name, Title: name})
+}
+
+func setupFakeAPI() *fakeAPI {
+	api := &fakeAPI{
+		accounts: map[string][]string{"foo@bar.com": {"Adobe"}},
+		domains:  map[string]gopwned.DomainBreaches{"bar.com": {"foo": {"Adobe"}}},
+	}
+	api.addBreach("Adobe")
+	return api
+}
+
+// recorder collects the keys of notified findings.
+type recorder struct {
+	keys []string
+	fail bool
+}
+
+func (r *recorder) Notify(f *notify.Finding) error {
+	if r.fail {
+		return errors.New("sink unavailable")
+	}
+	r.keys = append(r.keys, f.Key())
+	return nil
+}
+
+func setupWatcher(t *testing.T, api API, state string, sinks ...notify.Sink) *Watcher {
+	w, err := New(api, Config{Accounts: []string{"foo@bar.com", "clean@bar.com"}, Domains: []string{"bar.com"}, State: state}, sinks...)
+	if err != nil {
+		t.Fatalf("unable to create watcher: %v", err)
+	}
+	w.Logger = log.New(ioutil.Discard, "", 0)
+	return w
+}
+
+func TestPoll(t *testing.T) {
+	assert := assert.New(t)
+
+	api := setupFakeAPI()
+	rec := &recorder{}
+	w := setupWatcher(t, api, "", rec)
+
+	if _, err := w.Poll(); err != nil {
+		t.Fatalf("[TestPoll] returned error: %v", err)
+	}
+	sort.Strings(rec.keys)
+	assert.Equal([]string{"account:foo@bar.com:Adobe", "domain:bar.com:foo:Adobe"}, rec.keys, "[TestPoll] Expected every existing finding to be notified once.")
+
+	// Nothing new in the catalogue, the full list must not be downloaded.
+	notified, err := w.Poll()
+	if err != nil {
+		t.Fatalf("[TestPoll] returned error: %v", err)
+	}
+	assert.Empty(notified, "[TestPoll] Expected no findings without a new breach.")
+	assert.Equal(1, api.catalogueCalls, "[TestPoll] Expected the catalogue to be fetched only once.")
+
+	api.addBreach("Canva")
+	api.accounts["foo@bar.com"] = append(api.accounts["foo@bar.com"], "Canva")
+
+	notified, err = w.Poll()
+	if err != nil {
+		t.Fatalf("[TestPoll] returned error: %v", err)
+	}
+	if assert.Len(notified, 1, "[TestPoll] Expected a single new finding.") {
+		assert.Equal("account:foo@bar.com:Canva", notified[0].Key())
+		assert.Equal("Canva", notified[0].Breach.Title, "[TestPoll] Expected the finding to carry the full breach.")
+	}
+}
+
+func TestPollSurvivesRestart(t *testing.T) {
+	assert := assert.New(t)
+
+	state := filepath.Join(t.TempDir(), "state.json")
+	api := setupFakeAPI()
+
+	first := &recorder{}
+	if _, err := setupWatcher(t, api, state, first).Poll(); err != nil {
+		t.Fatalf("[TestPollSurvivesRestart] returned error: %v", err)
+	}
+	assert.Len(first.keys, 2)
+
+	// A new breach that does not involve any watched target.
+	api.addBreach("Canva")
+
+	second := &recorder{}
+	if _, err := setupWatcher(t, api, state, second).Poll(); err != nil {
+		t.Fatalf("[TestPollSurvivesRestart] returned error: %v", err)
+	}
+	assert.Empty(second.keys, "[TestPollSurvivesRestart] Expected no alerts to be repeated after a restart.")
+}
+
+func TestPollRetriesFailedSink(t *testing.T) {
+	assert := assert.New(t)
+
+	api := setupFakeAPI()
+	rec := &recorder{fail: true}
+	w := setupWatcher(t, api, "", rec)
+
+	notified, err := w.Poll()
+	if err != nil {
+		t.Fatalf("[TestPollRetriesFailedSink] returned error: %v", err)
+	}
+	assert.Empty(notified, "[TestPollRetriesFailedSink] Expected no findings to be notified.")
+
+	// Retried on the very next poll, without a new breach.
+	rec.fail = false
+	notified, err = w.Poll()
+	if err != nil {
+		t.Fatalf("[TestPollRetriesFailedSink] returned error: %v", err)
+	}
+	assert.Len(notified, 2, "[TestPollRetriesFailedSink] Expected failed findings to be notified again.")
+
+	// Every finding is delivered, so the catalogue is known now.
+	calls := api.catalogueCalls
+	notified, err = w.Poll()
+	if err != nil {
+		t.Fatalf("[TestPollRetriesFailedSink] returned error: %v", err)
+	}
+	assert.Empty(notified)
+	assert.Equal(calls, api.catalogueCalls, "[TestPollRetriesFailedSink] Expected the catalogue to be known once every finding was delivered.")
+}
+
+func TestPollRetriesOnlyFailedSinks(t *testing.T) {
+	assert := assert.New(t)
+
+	state := filepath.Join(t.TempDir(), "state.json")
+	api := setupFakeAPI()
+	ok, failing := &recorder{}, &recorder{fail: true}
+
+	if _, err := setupWatcher(t, api, state, ok, failing).Poll(); err != nil {
+		t.Fatalf("[TestPollRetriesOnlyFailedSinks] returned error: %v", err)
+	}
+	assert.Len(ok.keys, 2)
+
+	// Retried on the next poll, after a restart, without a new breach.
+	failing.fail = false
+	notified, err := setupWatcher(t, api, state, ok, failing).Poll()
+	if err != nil {
+		t.Fatalf("[TestPollRetriesOnlyFailedSinks] returned error: %v", err)
+	}
+	assert.Len(notified, 2, "[TestPollRetriesOnlyFailedSinks] Expected the findings to be notified once every sink got them.")
+	assert.Len(ok.keys, 2, "[TestPollRetriesOnlyFailedSinks] Expected no alert to be repeated to the sink that succeeded.")
+	assert.Len(failing.keys, 2, "[TestPollRetriesOnlyFailedSinks] Expected the failed sink to be retried.")
+}